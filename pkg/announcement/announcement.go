@@ -0,0 +1,223 @@
+package announcement
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Announcement is a banner shown to a targeted audience between StartsAt and EndsAt
+type Announcement struct {
+	ID       int64     `json:"id"`
+	Title    string    `json:"title"`
+	Body     string    `json:"body"`
+	Level    string    `json:"level"` // info, warning, critical
+	Plans    []string  `json:"plans,omitempty"`
+	Roles    []string  `json:"roles,omitempty"`
+	Percent  int       `json:"percent"` // Share of matching users (1-100) who see it, 0 on Create means 100
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+}
+
+// Audience describes the current user for targeting
+type Audience struct {
+	UserID string
+	Plan   string
+	Roles  []string
+}
+
+// Matches reports whether the announcement targets the audience
+func (a Announcement) Matches(aud Audience) bool {
+	if len(a.Plans) > 0 && !contains(a.Plans, aud.Plan) {
+		return false
+	}
+	if len(a.Roles) > 0 {
+		matched := false
+		for _, r := range aud.Roles {
+			if contains(a.Roles, r) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return inRollout(a.ID, aud.UserID, a.Percent)
+}
+
+// Store persists announcements and dismissals
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool}
+}
+
+// EnsureSchema creates the announcement tables if they don't exist
+func (s *Store) EnsureSchema(ctx context.Context) error {
+	_, err := s.pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS announcements (
+			id BIGSERIAL PRIMARY KEY,
+			title TEXT NOT NULL,
+			body TEXT NOT NULL,
+			level TEXT NOT NULL DEFAULT 'info',
+			plans TEXT[] NOT NULL DEFAULT '{}',
+			roles TEXT[] NOT NULL DEFAULT '{}',
+			percent INT NOT NULL DEFAULT 100 CHECK (percent BETWEEN 0 AND 100),
+			starts_at TIMESTAMPTZ NOT NULL,
+			ends_at TIMESTAMPTZ NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS announcement_dismissals (
+			announcement_id BIGINT NOT NULL REFERENCES announcements (id) ON DELETE CASCADE,
+			user_id TEXT NOT NULL,
+			dismissed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			PRIMARY KEY (announcement_id, user_id)
+		);`)
+	if err != nil {
+		return fmt.Errorf("error creating announcement schema: %w", err)
+	}
+	return nil
+}
+
+// Create stores a new announcement and sets its ID. Unset fields take the
+// column defaults: no targeting, info level and a 100% rollout.
+func (s *Store) Create(ctx context.Context, a *Announcement) error {
+	if a.Percent < 0 || a.Percent > 100 {
+		return fmt.Errorf("invalid announcement percent %d", a.Percent)
+	}
+	if a.Percent == 0 {
+		a.Percent = 100
+	}
+	if a.Level == "" {
+		a.Level = "info"
+	}
+	// pgx encodes nil slices as NULL, which the NOT NULL columns reject
+	if a.Plans == nil {
+		a.Plans = []string{}
+	}
+	if a.Roles == nil {
+		a.Roles = []string{}
+	}
+
+	err := s.pool.QueryRow(ctx, `
+		INSERT INTO announcements (title, body, level, plans, roles, percent, starts_at, ends_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+		a.Title, a.Body, a.Level, a.Plans, a.Roles, a.Percent, a.StartsAt, a.EndsAt,
+	).Scan(&a.ID)
+	if err != nil {
+		return fmt.Errorf("error creating announcement: %w", err)
+	}
+	return nil
+}
+
+// Active returns announcements currently live for the audience, excluding
+// ones the user has dismissed
+func (s *Store) Active(ctx context.Context, aud Audience) ([]Announcement, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT a.id, a.title, a.body, a.level, a.plans, a.roles, a.percent, a.starts_at, a.ends_at
+		FROM announcements a
+		WHERE now() BETWEEN a.starts_at AND a.ends_at
+		AND NOT EXISTS (
+			SELECT 1 FROM announcement_dismissals d
+			WHERE d.announcement_id = a.id AND d.user_id = $1
+		)
+		ORDER BY a.starts_at DESC`, aud.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading announcements: %w", err)
+	}
+	all, err := pgx.CollectRows(rows, pgx.RowToStructByPos[Announcement])
+	if err != nil {
+		return nil, fmt.Errorf("error loading announcements: %w", err)
+	}
+
+	active := make([]Announcement, 0, len(all))
+	for _, a := range all {
+		if a.Matches(aud) {
+			active = append(active, a)
+		}
+	}
+	return active, nil
+}
+
+// Dismiss hides the announcement for the user
+func (s *Store) Dismiss(ctx context.Context, announcementID int64, userID string) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO announcement_dismissals (announcement_id, user_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`, announcementID, userID)
+	if err != nil {
+		return fmt.Errorf("error dismissing announcement: %w", err)
+	}
+	return nil
+}
+
+// ListHandler returns the active announcements for the current user
+func (s *Store) ListHandler(audience func(*gin.Context) Audience) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		aud := audience(c)
+		if aud.UserID == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		active, err := s.Active(c.Request.Context(), aud)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"announcements": active})
+	}
+}
+
+// DismissHandler dismisses the announcement named by the :id path parameter
+func (s *Store) DismissHandler(audience func(*gin.Context) Audience) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		aud := audience(c)
+		if aud.UserID == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		var id int64
+		if _, err := fmt.Sscan(c.Param("id"), &id); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid announcement id"})
+			return
+		}
+
+		if err := s.Dismiss(c.Request.Context(), id, aud.UserID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// inRollout deterministically buckets a user so the same user always gets
+// the same answer for a given announcement
+func inRollout(id int64, userID string, percent int) bool {
+	if percent >= 100 {
+		return true
+	}
+	if percent <= 0 {
+		return false
+	}
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%d:%s", id, userID)
+	return int(h.Sum32()%100) < percent
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
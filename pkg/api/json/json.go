@@ -0,0 +1,205 @@
+package json
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Backend is a JSON implementation. The default wraps encoding/json; services
+// can plug in jsoniter or segmentio via SetBackend without changing callers.
+type Backend interface {
+	Marshal(v any) ([]byte, error)
+	NewEncoder(w io.Writer) Encoder
+}
+
+// Encoder matches the Encode method shared by the common JSON libraries
+type Encoder interface {
+	Encode(v any) error
+}
+
+type stdBackend struct{}
+
+func (stdBackend) Marshal(v any) ([]byte, error) { return stdjson.Marshal(v) }
+
+func (stdBackend) NewEncoder(w io.Writer) Encoder {
+	enc := stdjson.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return enc
+}
+
+var (
+	backendMu sync.RWMutex
+	backend   Backend = stdBackend{}
+)
+
+// SetBackend replaces the JSON implementation used by this package
+func SetBackend(b Backend) {
+	backendMu.Lock()
+	defer backendMu.Unlock()
+	backend = b
+}
+
+func current() Backend {
+	backendMu.RLock()
+	defer backendMu.RUnlock()
+	return backend
+}
+
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// Encode writes v to w using a pooled buffer, so the response is written in a
+// single call and a failed encode never leaves a partial body
+func Encode(w io.Writer, v any) error {
+	return encode(v, func(b []byte) error {
+		_, err := w.Write(b)
+		return err
+	})
+}
+
+// Write renders v as the JSON response body with the given status. The body
+// is encoded before anything is sent, so an encode failure still results in a
+// 500 rather than the intended status with an empty body.
+func Write(c *gin.Context, status int, v any) {
+	err := encode(v, func(b []byte) error {
+		c.Header("Content-Type", "application/json; charset=utf-8")
+		c.Status(status)
+		_, err := c.Writer.Write(b)
+		return err
+	})
+	if err != nil {
+		_ = c.Error(err)
+		if !c.Writer.Written() {
+			c.AbortWithStatus(http.StatusInternalServerError)
+		}
+	}
+}
+
+// encode renders v into a pooled buffer and hands the bytes to write
+func encode(v any, write func([]byte) error) error {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer func() {
+		// Don't keep oversized buffers from one large response around
+		if buf.Cap() <= 1<<20 {
+			bufferPool.Put(buf)
+		}
+	}()
+
+	if err := current().NewEncoder(buf).Encode(v); err != nil {
+		return err
+	}
+	return write(buf.Bytes())
+}
+
+// ArrayWriter streams a JSON array element by element so large lists never
+// need to be held in memory as a single slice
+type ArrayWriter struct {
+	w     io.Writer
+	count int
+	err   error
+}
+
+func NewArrayWriter(w io.Writer) *ArrayWriter {
+	return &ArrayWriter{w: w}
+}
+
+// Write appends one element to the array
+func (a *ArrayWriter) Write(v any) error {
+	if a.err != nil {
+		return a.err
+	}
+
+	b, err := current().Marshal(v)
+	if err != nil {
+		a.err = err
+		return err
+	}
+
+	prefix := ","
+	if a.count == 0 {
+		prefix = "["
+	}
+	if _, err := io.WriteString(a.w, prefix); err != nil {
+		a.err = err
+		return err
+	}
+	if _, a.err = a.w.Write(b); a.err != nil {
+		return a.err
+	}
+
+	a.count++
+	if f, ok := a.w.(interface{ Flush() }); ok && a.count%100 == 0 {
+		f.Flush()
+	}
+	return nil
+}
+
+// Close terminates the array; an empty array is written as []
+func (a *ArrayWriter) Close() error {
+	if a.err != nil {
+		return a.err
+	}
+	if a.count == 0 {
+		_, err := io.WriteString(a.w, "[]")
+		return err
+	}
+	_, err := io.WriteString(a.w, "]")
+	return err
+}
+
+// Time always encodes as UTC RFC 3339 with millisecond precision
+type Time time.Time
+
+const timeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+func (t Time) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(time.Time(t).UTC().Format(timeLayout))), nil
+}
+
+func (t *Time) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return err
+	}
+	*t = Time(parsed)
+	return nil
+}
+
+// Decimal encodes a number with a fixed count of decimal places (e.g. 2 for
+// currency) instead of float64's shortest representation
+type Decimal struct {
+	Value  float64
+	Places int
+}
+
+// MarshalJSON fails for NaN and infinities, which JSON cannot represent
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	if math.IsNaN(d.Value) || math.IsInf(d.Value, 0) {
+		return nil, fmt.Errorf("json: unsupported decimal value %v", d.Value)
+	}
+	return []byte(strconv.FormatFloat(d.Value, 'f', d.Places, 64)), nil
+}
+
+func (d *Decimal) UnmarshalJSON(b []byte) error {
+	v, err := strconv.ParseFloat(string(b), 64)
+	if err != nil {
+		return err
+	}
+	d.Value = v
+	return nil
+}
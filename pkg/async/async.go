@@ -0,0 +1,174 @@
+package async
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ranson21/ranor-common/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Runner starts named background goroutines that can't crash the process
+type Runner struct {
+	log      logger.Logger
+	inFlight atomic.Int64
+
+	mu     sync.Mutex
+	byName map[string]int64
+}
+
+var std atomic.Pointer[Runner]
+
+func init() {
+	// Discard logs until the service installs its own runner with SetDefault
+	std.Store(NewRunner(logger.NewNop()))
+}
+
+// SetDefault sets the runner used by the package-level Go and InFlight
+func SetDefault(r *Runner) {
+	std.Store(r)
+}
+
+// Go runs fn on the default runner. Panics are still recovered before
+// SetDefault is called, but they are only logged once it has been.
+func Go(ctx context.Context, name string, fn func(ctx context.Context) error) {
+	std.Load().Go(ctx, name, fn)
+}
+
+// InFlight returns the number of goroutines running on the default runner
+func InFlight() int64 {
+	return std.Load().InFlight()
+}
+
+func NewRunner(log logger.Logger) *Runner {
+	return &Runner{
+		log:    log,
+		byName: make(map[string]int64),
+	}
+}
+
+// Go runs fn in a goroutine. A panic is recovered and logged with the
+// goroutine's name and stack; a returned error is logged too.
+func (r *Runner) Go(ctx context.Context, name string, fn func(ctx context.Context) error) {
+	r.track(name, 1)
+	go func() {
+		defer r.track(name, -1)
+		if err := r.run(ctx, name, fn); err != nil && ctx.Err() == nil {
+			r.log.Error("background task failed", zap.String("task", name), zap.Error(err))
+		}
+	}()
+}
+
+// InFlight returns the number of running goroutines started by this runner
+func (r *Runner) InFlight() int64 {
+	return r.inFlight.Load()
+}
+
+// InFlightByName returns the running goroutine count per task name
+func (r *Runner) InFlightByName() map[string]int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counts := make(map[string]int64, len(r.byName))
+	for name, n := range r.byName {
+		counts[name] = n
+	}
+	return counts
+}
+
+func (r *Runner) run(ctx context.Context, name string, fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			r.log.Error("panic recovered in background task",
+				zap.String("task", name),
+				zap.Any("error", rec),
+				zap.String("stack", string(debug.Stack())),
+			)
+			err = fmt.Errorf("panic in %s: %v", name, rec)
+		}
+	}()
+	return fn(ctx)
+}
+
+func (r *Runner) track(name string, delta int64) {
+	r.inFlight.Add(delta)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.byName[name] += delta
+	if r.byName[name] == 0 {
+		delete(r.byName, name)
+	}
+}
+
+// Group runs a bounded number of tasks concurrently. The first error (or
+// recovered panic) cancels the group's context and is returned by Wait.
+type Group struct {
+	runner *Runner
+	ctx    context.Context
+	cancel context.CancelFunc
+	sem    chan struct{}
+	wg     sync.WaitGroup
+
+	once sync.Once
+	err  error
+}
+
+// NewGroup creates a group limited to limit concurrent tasks (0 = unlimited)
+func (r *Runner) NewGroup(ctx context.Context, limit int) *Group {
+	ctx, cancel := context.WithCancel(ctx)
+	g := &Group{runner: r, ctx: ctx, cancel: cancel}
+	if limit > 0 {
+		g.sem = make(chan struct{}, limit)
+	}
+	return g
+}
+
+// Go waits for a free slot and runs fn. It returns without running fn if the
+// group's context is already cancelled.
+func (g *Group) Go(name string, fn func(ctx context.Context) error) {
+	if g.ctx.Err() != nil {
+		return
+	}
+	if g.sem != nil {
+		select {
+		case g.sem <- struct{}{}:
+		case <-g.ctx.Done():
+			return
+		}
+		// select picks randomly when both are ready
+		if g.ctx.Err() != nil {
+			<-g.sem
+			return
+		}
+	}
+
+	g.wg.Add(1)
+	g.runner.track(name, 1)
+	go func() {
+		defer func() {
+			g.runner.track(name, -1)
+			if g.sem != nil {
+				<-g.sem
+			}
+			g.wg.Done()
+		}()
+
+		if err := g.runner.run(g.ctx, name, fn); err != nil {
+			g.once.Do(func() {
+				g.err = err
+				g.cancel()
+			})
+		}
+	}()
+}
+
+// Wait blocks until every task has finished and returns the first error
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+	return g.err
+}
@@ -0,0 +1,35 @@
+package async
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Collector exports the in-flight goroutine counts of a Runner
+type Collector struct {
+	runner   *Runner
+	inFlight *prometheus.Desc
+}
+
+// Ensure Collector implements prometheus.Collector
+var _ prometheus.Collector = (*Collector)(nil)
+
+// NewCollector exports r's counts, or those of the current default runner
+// when r is nil
+func NewCollector(r *Runner) *Collector {
+	return &Collector{
+		runner:   r,
+		inFlight: prometheus.NewDesc("async_tasks_in_flight", "Background goroutines currently running.", []string{"task"}, nil),
+	}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.inFlight
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	r := c.runner
+	if r == nil {
+		r = std.Load()
+	}
+	for name, n := range r.InFlightByName() {
+		ch <- prometheus.MustNewConstMetric(c.inFlight, prometheus.GaugeValue, float64(n), name)
+	}
+}
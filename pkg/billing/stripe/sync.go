@@ -0,0 +1,147 @@
+package stripe
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Customer is the subset of a Stripe customer object kept locally
+type Customer struct {
+	ID       string            `json:"id"`
+	Email    string            `json:"email"`
+	Name     string            `json:"name"`
+	Metadata map[string]string `json:"metadata"`
+	Deleted  bool              `json:"deleted"`
+}
+
+// Subscription is the subset of a Stripe subscription object kept locally
+type Subscription struct {
+	ID                string `json:"id"`
+	Customer          string `json:"customer"`
+	Status            string `json:"status"`
+	CurrentPeriodEnd  int64  `json:"current_period_end"`
+	CancelAtPeriodEnd bool   `json:"cancel_at_period_end"`
+	Items             struct {
+		Data []struct {
+			Price struct {
+				ID string `json:"id"`
+			} `json:"price"`
+		} `json:"data"`
+	} `json:"items"`
+}
+
+// Sync mirrors customers and subscriptions into tables in the given schema
+type Sync struct {
+	schema string
+}
+
+func NewSync(schema string) *Sync {
+	return &Sync{schema: schema}
+}
+
+// EnsureSchema creates the mirror tables if they don't exist
+func (s *Sync) EnsureSchema(ctx context.Context, pool *pgxpool.Pool) error {
+	_, err := pool.Exec(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id TEXT PRIMARY KEY,
+			email TEXT NOT NULL DEFAULT '',
+			name TEXT NOT NULL DEFAULT '',
+			metadata JSONB NOT NULL DEFAULT '{}',
+			deleted BOOLEAN NOT NULL DEFAULT false,
+			updated_at TIMESTAMPTZ NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS %s (
+			id TEXT PRIMARY KEY,
+			customer_id TEXT NOT NULL,
+			status TEXT NOT NULL,
+			price_id TEXT NOT NULL DEFAULT '',
+			current_period_end TIMESTAMPTZ NOT NULL,
+			cancel_at_period_end BOOLEAN NOT NULL DEFAULT false,
+			updated_at TIMESTAMPTZ NOT NULL
+		);`, s.table("stripe_customers"), s.table("stripe_subscriptions")))
+	if err != nil {
+		return fmt.Errorf("error creating stripe sync tables: %w", err)
+	}
+	return nil
+}
+
+// Register wires the sync handlers into the webhook
+func (s *Sync) Register(w *Webhook) {
+	for _, t := range []string{"customer.created", "customer.updated", "customer.deleted"} {
+		w.On(t, s.syncCustomer)
+	}
+	for _, t := range []string{
+		"customer.subscription.created",
+		"customer.subscription.updated",
+		"customer.subscription.deleted",
+	} {
+		w.On(t, s.syncSubscription)
+	}
+}
+
+func (s *Sync) syncCustomer(ctx context.Context, tx pgx.Tx, event *Event) error {
+	var c Customer
+	if err := json.Unmarshal(event.Data.Object, &c); err != nil {
+		return fmt.Errorf("error decoding customer: %w", err)
+	}
+	if event.Type == "customer.deleted" {
+		c.Deleted = true
+	}
+
+	metadata, err := json.Marshal(c.Metadata)
+	if err != nil {
+		return fmt.Errorf("error encoding customer metadata: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, fmt.Sprintf(`
+		INSERT INTO %s (id, email, name, metadata, deleted, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (id) DO UPDATE SET
+			email = EXCLUDED.email, name = EXCLUDED.name, metadata = EXCLUDED.metadata,
+			deleted = EXCLUDED.deleted, updated_at = EXCLUDED.updated_at
+		WHERE %[1]s.updated_at <= EXCLUDED.updated_at`, s.table("stripe_customers")),
+		c.ID, c.Email, c.Name, metadata, c.Deleted, time.Unix(event.Created, 0))
+	if err != nil {
+		return fmt.Errorf("error syncing customer %s: %w", c.ID, err)
+	}
+	return nil
+}
+
+func (s *Sync) syncSubscription(ctx context.Context, tx pgx.Tx, event *Event) error {
+	var sub Subscription
+	if err := json.Unmarshal(event.Data.Object, &sub); err != nil {
+		return fmt.Errorf("error decoding subscription: %w", err)
+	}
+
+	var priceID string
+	if len(sub.Items.Data) > 0 {
+		priceID = sub.Items.Data[0].Price.ID
+	}
+
+	_, err := tx.Exec(ctx, fmt.Sprintf(`
+		INSERT INTO %s (id, customer_id, status, price_id, current_period_end, cancel_at_period_end, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (id) DO UPDATE SET
+			customer_id = EXCLUDED.customer_id, status = EXCLUDED.status, price_id = EXCLUDED.price_id,
+			current_period_end = EXCLUDED.current_period_end,
+			cancel_at_period_end = EXCLUDED.cancel_at_period_end, updated_at = EXCLUDED.updated_at
+		WHERE %[1]s.updated_at <= EXCLUDED.updated_at`, s.table("stripe_subscriptions")),
+		sub.ID, sub.Customer, sub.Status, priceID, time.Unix(sub.CurrentPeriodEnd, 0),
+		sub.CancelAtPeriodEnd, time.Unix(event.Created, 0))
+	if err != nil {
+		return fmt.Errorf("error syncing subscription %s: %w", sub.ID, err)
+	}
+	return nil
+}
+
+func (s *Sync) table(name string) string {
+	if s.schema == "" {
+		return pgx.Identifier{name}.Sanitize()
+	}
+	return pgx.Identifier{s.schema, name}.Sanitize()
+}
@@ -0,0 +1,194 @@
+package stripe
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ranson21/ranor-common/pkg/logger"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidSignature = errors.New("stripe: invalid webhook signature")
+	ErrTimestampExpired = errors.New("stripe: webhook timestamp outside tolerance")
+)
+
+// Event is a Stripe webhook event. Data.Object holds the raw resource.
+type Event struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Created int64  `json:"created"`
+	Data    struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// HandlerFunc processes a single event type. Writes made through tx commit
+// together with the event's idempotency record.
+type HandlerFunc func(ctx context.Context, tx pgx.Tx, event *Event) error
+
+// Webhook verifies and dispatches Stripe webhook deliveries. Each event ID is
+// recorded in the processed events table so redeliveries are acknowledged
+// without running handlers twice.
+type Webhook struct {
+	secret    string
+	tolerance time.Duration
+	pool      *pgxpool.Pool
+	log       logger.Logger
+
+	mu       sync.RWMutex
+	handlers map[string]HandlerFunc
+}
+
+func NewWebhook(secret string, pool *pgxpool.Pool, log logger.Logger) *Webhook {
+	return &Webhook{
+		secret:    secret,
+		tolerance: 5 * time.Minute,
+		pool:      pool,
+		log:       log,
+		handlers:  make(map[string]HandlerFunc),
+	}
+}
+
+// On registers the handler for an event type such as "customer.updated"
+func (w *Webhook) On(eventType string, fn HandlerFunc) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.handlers[eventType] = fn
+}
+
+// EnsureSchema creates the processed events table if it doesn't exist
+func (w *Webhook) EnsureSchema(ctx context.Context) error {
+	_, err := w.pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS stripe_processed_events (
+			event_id TEXT PRIMARY KEY,
+			event_type TEXT NOT NULL,
+			processed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`)
+	if err != nil {
+		return fmt.Errorf("error creating stripe events table: %w", err)
+	}
+	return nil
+}
+
+// Verify checks the Stripe-Signature header against the payload
+func (w *Webhook) Verify(payload []byte, header string, now time.Time) error {
+	var (
+		timestamp  int64
+		signatures []string
+	)
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			ts, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return ErrInvalidSignature
+			}
+			timestamp = ts
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == 0 || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+
+	if d := now.Sub(time.Unix(timestamp, 0)); d > w.tolerance || d < -w.tolerance {
+		return ErrTimestampExpired
+	}
+
+	mac := hmac.New(sha256.New, []byte(w.secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(payload)
+	expected := mac.Sum(nil)
+
+	for _, sig := range signatures {
+		decoded, err := hex.DecodeString(sig)
+		if err == nil && hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// ServeHTTP verifies the delivery, skips already processed events and
+// dispatches to the registered handler inside a transaction with the
+// idempotency record.
+func (w *Webhook) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(rw, "unable to read body", http.StatusBadRequest)
+		return
+	}
+
+	if err := w.Verify(payload, r.Header.Get("Stripe-Signature"), time.Now()); err != nil {
+		w.log.Warn("rejected stripe webhook", zap.Error(err))
+		http.Error(rw, "invalid signature", http.StatusBadRequest)
+		return
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		http.Error(rw, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	if err := w.process(r.Context(), &event); err != nil {
+		w.log.Error("stripe webhook processing failed",
+			zap.String("event_id", event.ID),
+			zap.String("event_type", event.Type),
+			zap.Error(err),
+		)
+		http.Error(rw, "processing failed", http.StatusInternalServerError)
+		return
+	}
+
+	rw.WriteHeader(http.StatusOK)
+}
+
+func (w *Webhook) process(ctx context.Context, event *Event) error {
+	w.mu.RLock()
+	fn, exists := w.handlers[event.Type]
+	w.mu.RUnlock()
+
+	tx, err := w.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx,
+		"INSERT INTO stripe_processed_events (event_id, event_type) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		event.ID, event.Type)
+	if err != nil {
+		return fmt.Errorf("error recording event: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil // Already processed
+	}
+
+	if exists {
+		if err := fn(ctx, tx, event); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,66 @@
+package stripe
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+const testSecret = "whsec_test"
+
+func sign(secret string, timestamp int64, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.%s", timestamp, payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebhookVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	ts := now.Unix()
+	payload := `{"id":"evt_1"}`
+	valid := sign(testSecret, ts, payload)
+
+	tests := []struct {
+		name   string
+		header string
+		want   error
+	}{
+		{"valid", fmt.Sprintf("t=%d,v1=%s", ts, valid), nil},
+		{"valid among several signatures", fmt.Sprintf("t=%d,v1=%s,v1=%s", ts, sign("old", ts, payload), valid), nil},
+		{"spaces around parts", fmt.Sprintf("t=%d, v1=%s", ts, valid), nil},
+		{"within tolerance", fmt.Sprintf("t=%d,v1=%s", ts-240, sign(testSecret, ts-240, payload)), nil},
+		{"empty header", "", ErrInvalidSignature},
+		{"missing timestamp", "v1=" + valid, ErrInvalidSignature},
+		{"missing signature", fmt.Sprintf("t=%d", ts), ErrInvalidSignature},
+		{"malformed timestamp", "t=soon,v1=" + valid, ErrInvalidSignature},
+		{"wrong secret", fmt.Sprintf("t=%d,v1=%s", ts, sign("other", ts, payload)), ErrInvalidSignature},
+		{"signature not hex", fmt.Sprintf("t=%d,v1=zz", ts), ErrInvalidSignature},
+		{"timestamp not signed", fmt.Sprintf("t=%d,v1=%s", ts+1, valid), ErrInvalidSignature},
+		{"too old", fmt.Sprintf("t=%d,v1=%s", ts-600, sign(testSecret, ts-600, payload)), ErrTimestampExpired},
+		{"too far ahead", fmt.Sprintf("t=%d,v1=%s", ts+600, sign(testSecret, ts+600, payload)), ErrTimestampExpired},
+	}
+
+	w := NewWebhook(testSecret, nil, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := w.Verify([]byte(payload), tt.header, now)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Verify = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookVerifyRejectsChangedPayload(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	header := fmt.Sprintf("t=%d,v1=%s", now.Unix(), sign(testSecret, now.Unix(), `{"id":"evt_1"}`))
+
+	err := NewWebhook(testSecret, nil, nil).Verify([]byte(`{"id":"evt_2"}`), header, now)
+	if !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify = %v, want %v", err, ErrInvalidSignature)
+	}
+}
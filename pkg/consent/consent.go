@@ -0,0 +1,150 @@
+package consent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrPolicyNotFound is returned when no policy document exists for a kind
+var ErrPolicyNotFound = errors.New("consent: policy not found")
+
+// Policy is a versioned policy document a user can consent to (e.g. cookies, marketing)
+type Policy struct {
+	Kind        string    `json:"kind"`
+	Version     int       `json:"version"`
+	Document    string    `json:"document"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// Consent is a user's current decision for a policy kind
+type Consent struct {
+	UserID        string     `json:"user_id"`
+	Kind          string     `json:"kind"`
+	PolicyVersion int        `json:"policy_version"`
+	GrantedAt     time.Time  `json:"granted_at"`
+	WithdrawnAt   *time.Time `json:"withdrawn_at,omitempty"`
+}
+
+// Active reports whether the consent is granted and not withdrawn
+func (c Consent) Active() bool {
+	return c.WithdrawnAt == nil
+}
+
+// Store persists policies and consents
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool}
+}
+
+// EnsureSchema creates the consent tables if they don't exist
+func (s *Store) EnsureSchema(ctx context.Context) error {
+	_, err := s.pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS consent_policies (
+			kind TEXT NOT NULL,
+			version INT NOT NULL,
+			document TEXT NOT NULL,
+			published_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			PRIMARY KEY (kind, version)
+		);
+		CREATE TABLE IF NOT EXISTS consents (
+			id BIGSERIAL PRIMARY KEY,
+			user_id TEXT NOT NULL,
+			kind TEXT NOT NULL,
+			policy_version INT NOT NULL,
+			granted_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			withdrawn_at TIMESTAMPTZ,
+			FOREIGN KEY (kind, policy_version) REFERENCES consent_policies (kind, version)
+		);
+		CREATE INDEX IF NOT EXISTS consents_user_kind_idx ON consents (user_id, kind, granted_at DESC);`)
+	if err != nil {
+		return fmt.Errorf("error creating consent schema: %w", err)
+	}
+	return nil
+}
+
+// PublishPolicy stores a new version of a policy document and returns it
+func (s *Store) PublishPolicy(ctx context.Context, kind, document string) (*Policy, error) {
+	p := &Policy{Kind: kind, Document: document}
+	err := s.pool.QueryRow(ctx, `
+		INSERT INTO consent_policies (kind, version, document)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2 FROM consent_policies WHERE kind = $1
+		RETURNING version, published_at`, kind, document,
+	).Scan(&p.Version, &p.PublishedAt)
+	if err != nil {
+		return nil, fmt.Errorf("error publishing policy: %w", err)
+	}
+	return p, nil
+}
+
+// LatestPolicy returns the newest version of a policy kind
+func (s *Store) LatestPolicy(ctx context.Context, kind string) (*Policy, error) {
+	p := &Policy{Kind: kind}
+	err := s.pool.QueryRow(ctx, `
+		SELECT version, document, published_at FROM consent_policies
+		WHERE kind = $1 ORDER BY version DESC LIMIT 1`, kind,
+	).Scan(&p.Version, &p.Document, &p.PublishedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrPolicyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error loading policy: %w", err)
+	}
+	return p, nil
+}
+
+// Grant records the user's consent to the given policy version
+func (s *Store) Grant(ctx context.Context, userID, kind string, version int) error {
+	_, err := s.pool.Exec(ctx,
+		"INSERT INTO consents (user_id, kind, policy_version) VALUES ($1, $2, $3)",
+		userID, kind, version,
+	)
+	if err != nil {
+		return fmt.Errorf("error granting consent: %w", err)
+	}
+	return nil
+}
+
+// Withdraw marks the user's active consent for a kind as withdrawn
+func (s *Store) Withdraw(ctx context.Context, userID, kind string) error {
+	_, err := s.pool.Exec(ctx,
+		"UPDATE consents SET withdrawn_at = now() WHERE user_id = $1 AND kind = $2 AND withdrawn_at IS NULL",
+		userID, kind,
+	)
+	if err != nil {
+		return fmt.Errorf("error withdrawing consent: %w", err)
+	}
+	return nil
+}
+
+// Current returns the user's most recent consent decision per kind
+func (s *Store) Current(ctx context.Context, userID string) (map[string]Consent, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT DISTINCT ON (kind) kind, policy_version, granted_at, withdrawn_at
+		FROM consents WHERE user_id = $1
+		ORDER BY kind, granted_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading consents: %w", err)
+	}
+	defer rows.Close()
+
+	consents := make(map[string]Consent)
+	for rows.Next() {
+		c := Consent{UserID: userID}
+		if err := rows.Scan(&c.Kind, &c.PolicyVersion, &c.GrantedAt, &c.WithdrawnAt); err != nil {
+			return nil, fmt.Errorf("error scanning consent: %w", err)
+		}
+		consents[c.Kind] = c
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error loading consents: %w", err)
+	}
+	return consents, nil
+}
@@ -0,0 +1,47 @@
+package consent
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ranson21/ranor-common/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type contextKey struct{}
+
+// Middleware loads the current user's consents and stores them on the request
+// context. userID extracts the authenticated user; requests without one pass
+// through with no consents attached.
+func Middleware(store *Store, log logger.Logger, userID func(*gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := userID(c)
+		if id == "" {
+			c.Next()
+			return
+		}
+
+		consents, err := store.Current(c.Request.Context(), id)
+		if err != nil {
+			log.Error("error loading consents", zap.String("user_id", id), zap.Error(err))
+			c.Next()
+			return
+		}
+
+		ctx := context.WithValue(c.Request.Context(), contextKey{}, consents)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// FromContext returns the consents loaded by Middleware, keyed by policy kind
+func FromContext(ctx context.Context) map[string]Consent {
+	consents, _ := ctx.Value(contextKey{}).(map[string]Consent)
+	return consents
+}
+
+// Granted reports whether the request's user has an active consent for kind
+func Granted(ctx context.Context, kind string) bool {
+	c, ok := FromContext(ctx)[kind]
+	return ok && c.Active()
+}
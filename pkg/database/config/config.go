@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"strconv"
+	"strings"
 	"time"
+
+	"github.com/ranson21/ranor-common/pkg/env"
 )
 
 type Environment string
@@ -17,78 +19,70 @@ const (
 )
 
 type DatabaseConfig struct {
+	Environment  Environment
 	Host         string
 	Port         string
 	User         string
 	Password     string
 	DBName       string
 	Schema       string
-	SSLMode      string
+	SSLMode      string // disable, require, verify-ca or verify-full
+	SSLRootCert  string // CA bundle used by verify-ca and verify-full, system roots when empty
+	SSLCert      string // Client certificate for servers requiring mutual TLS
+	SSLKey       string // Key for SSLCert
 	MaxConns     int32
 	MinConns     int32
 	MaxIdleTime  time.Duration
 	MaxLifetime  time.Duration
 	UseIAMAuth   bool
-	InstanceName string // For Cloud SQL
+	InstanceName string   // For Cloud SQL
+	ReplicaDSNs  []string `fingerprint:"-"` // Connection strings for read replicas, usually embedding passwords
+
+	// Prepared statement cache
+	StatementCacheMode     string // cache_statement, cache_describe, describe_exec, exec or simple_protocol
+	StatementCacheCapacity int    // Statements cached per connection, 0 keeps the pgx default
+
+	// Timeouts, 0 disables each one
+	ConnectTimeout           time.Duration // Establishing a new connection
+	StatementTimeout         time.Duration // Any single statement
+	IdleInTransactionTimeout time.Duration // Sessions left idle inside an open transaction
+
+	// Credentials resolves Password at connect time when set
+	Credentials CredentialProvider
+
+	// Startup connection retry
+	ConnectAttempts   int           // Maximum connection attempts, 1 disables retrying
+	ConnectBackoff    time.Duration // Initial delay between attempts, doubled each time
+	ConnectMaxElapsed time.Duration // Give up once this much time has passed
+	ConnectJitter     float64       // Random fraction (0-1) added to or removed from each delay
 }
 
-func NewDatabaseConfig(env Environment, service string) *DatabaseConfig {
-	// MaxConns: Fetch from ENV, default to 50
-	maxConns := 50
-	if val := os.Getenv("PG_MAX_CONNS"); val != "" {
-		if parsedVal, err := strconv.Atoi(val); err == nil {
-			maxConns = parsedVal
-		} else {
-			log.Printf("Invalid value for PG_MAX_CONNS, falling back to default: %d", maxConns)
-		}
-	}
-
-	// MinConns: Fetch from ENV, default to 5
-	minConns := 5
-	if val := os.Getenv("PG_MIN_CONNS"); val != "" {
-		if parsedVal, err := strconv.Atoi(val); err == nil {
-			minConns = parsedVal
-		} else {
-			log.Printf("Invalid value for PG_MIN_CONNS, falling back to default: %d", minConns)
-		}
-	}
-
-	// MaxConnIdleTime: Fetch from ENV, default to 5 minutes
-	maxConnIdleTime := 5 * time.Minute
-	if val := os.Getenv("PG_MAX_IDLE_TIME"); val != "" {
-		if parsedVal, err := time.ParseDuration(val); err == nil {
-			maxConnIdleTime = parsedVal
-		} else {
-			log.Printf("Invalid value for PG_MAX_IDLE_TIME, falling back to default: %v", maxConnIdleTime)
-		}
-	}
-
-	// MaxConnLifetime: Fetch from ENV, default to 1 hour
-	maxConnLifetime := 1 * time.Hour
-	if val := os.Getenv("PG_MAX_LIFETIME"); val != "" {
-		if parsedVal, err := time.ParseDuration(val); err == nil {
-			maxConnLifetime = parsedVal
-		} else {
-			log.Printf("Invalid value for PG_MAX_LIFETIME, falling back to default: %v", maxConnLifetime)
-		}
+// NewDatabaseConfig builds the config for environment from defaults and ENV,
+// then applies opts. It returns an error for unknown environments or a config
+// that fails Validate.
+func NewDatabaseConfig(environment Environment, service string, opts ...Option) (*DatabaseConfig, error) {
+	config := defaults()
+
+	p := env.New()
+	sslMode := env.Get(p, "PG_SSL_MODE", "")
+	sslRootCert := env.Get(p, "PG_SSL_ROOT_CERT", "")
+	sslCert := env.Get(p, "PG_SSL_CERT", "")
+	sslKey := env.Get(p, "PG_SSL_KEY", "")
+	if err := p.Err(); err != nil {
+		log.Printf("Invalid database TLS settings, falling back to defaults: %v", err)
 	}
 
-	switch env {
+	config.Environment = environment
+	switch environment {
 	case Local:
 		// Use local postgres
-		return &DatabaseConfig{
-			Host:        "localhost",
-			Port:        "5432", // Local postgres port
-			User:        "postgres",
-			Password:    "postgres",
-			DBName:      "ranor",
-			Schema:      service,
-			SSLMode:     "disable",
-			MaxConns:    int32(maxConns),
-			MinConns:    int32(minConns),
-			MaxIdleTime: maxConnIdleTime,
-			MaxLifetime: maxConnLifetime,
-		}
+		config.Host = "localhost"
+		config.User = "postgres"
+		config.Password = "postgres"
+		config.DBName = "ranor"
+		config.Schema = service
+		config.SSLMode = "disable"
+		// Statement timeouts stay unlimited locally unless overridden
 	case Development, Production:
 		// Use Cloud SQL
 		dbUser := os.Getenv(fmt.Sprintf("%s_DB_USER", service))
@@ -96,29 +90,90 @@ func NewDatabaseConfig(env Environment, service string) *DatabaseConfig {
 			dbUser = "postgres" // Default Cloud SQL user
 		}
 
-		config := &DatabaseConfig{
-			Host:         "localhost", // Cloud SQL Proxy always runs locally
-			Port:         "5432",
-			User:         dbUser,
-			Password:     os.Getenv(fmt.Sprintf("%s_DB_PASSWORD", service)),
-			DBName:       fmt.Sprintf("ranor_%s", service),
-			SSLMode:      "disable",         // Proxy handles encryption
-			UseIAMAuth:   env == Production, // Use IAM auth in production
-			InstanceName: os.Getenv("INSTANCE_CONNECTION_NAME"),
-			MaxConns:     int32(maxConns),
-			MinConns:     int32(minConns),
-			MaxIdleTime:  maxConnIdleTime,
-			MaxLifetime:  maxConnLifetime,
+		config.Host = "localhost" // Cloud SQL Proxy always runs locally
+		config.User = dbUser
+		config.Password = os.Getenv(fmt.Sprintf("%s_DB_PASSWORD", service))
+		config.DBName = fmt.Sprintf("ranor_%s", service)
+		config.SSLMode = "disable"                    // Proxy handles encryption
+		config.UseIAMAuth = environment == Production // Use IAM auth in production
+		config.InstanceName = os.Getenv("INSTANCE_CONNECTION_NAME")
+
+		// Keep runaway queries from holding Cloud SQL connections indefinitely
+		if config.StatementTimeout == 0 && os.Getenv("PG_STATEMENT_TIMEOUT") == "" {
+			config.StatementTimeout = 30 * time.Second
+		}
+		if config.IdleInTransactionTimeout == 0 && os.Getenv("PG_IDLE_IN_TRANSACTION_TIMEOUT") == "" {
+			config.IdleInTransactionTimeout = 60 * time.Second
 		}
 
-		if env == Development {
+		if environment == Development {
 			config.Schema = service // Use schemas in dev
 		}
-
-		return config
 	default:
-		panic("unknown environment")
+		return nil, fmt.Errorf("%w: %q", ErrUnknownEnvironment, environment)
+	}
+
+	// Client certificates apply to any environment connecting without the proxy
+	config.SSLRootCert = sslRootCert
+	config.SSLCert = sslCert
+	config.SSLKey = sslKey
+	switch {
+	case sslMode != "":
+		config.SSLMode = sslMode
+	case sslRootCert != "":
+		// A CA bundle is only useful when verifying the server
+		config.SSLMode = "verify-full"
+	case sslCert != "":
+		config.SSLMode = "require"
+	}
+
+	// A single DATABASE_URL (Heroku, Render, CI) overrides the per-environment defaults
+	if rawURL := os.Getenv("DATABASE_URL"); rawURL != "" {
+		if err := config.ApplyURL(rawURL); err != nil {
+			return nil, fmt.Errorf("error applying DATABASE_URL: %w", err)
+		}
+	}
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// defaults returns the settings shared by every config: the postgres port
+// plus pool, statement cache, timeout and retry settings from ENV, falling
+// back to defaults on invalid values
+func defaults() *DatabaseConfig {
+	p := env.New()
+	config := &DatabaseConfig{
+		Port:        "5432",
+		MaxConns:    env.Get(p, "PG_MAX_CONNS", int32(50)),
+		MinConns:    env.Get(p, "PG_MIN_CONNS", int32(5)),
+		MaxIdleTime: env.Get(p, "PG_MAX_IDLE_TIME", 5*time.Minute),
+		MaxLifetime: env.Get(p, "PG_MAX_LIFETIME", 1*time.Hour),
+		ReplicaDSNs: env.Get(p, "PG_REPLICA_DSNS", []string(nil)),
+
+		StatementCacheMode:     env.Get(p, "PG_STATEMENT_CACHE_MODE", "cache_statement"),
+		StatementCacheCapacity: env.Get(p, "PG_STATEMENT_CACHE_CAPACITY", 0),
+
+		ConnectTimeout:           env.Get(p, "PG_CONNECT_TIMEOUT", 10*time.Second),
+		StatementTimeout:         env.Get(p, "PG_STATEMENT_TIMEOUT", time.Duration(0)),
+		IdleInTransactionTimeout: env.Get(p, "PG_IDLE_IN_TRANSACTION_TIMEOUT", time.Duration(0)),
+
+		ConnectAttempts:   env.Get(p, "PG_CONNECT_ATTEMPTS", 5),
+		ConnectBackoff:    env.Get(p, "PG_CONNECT_BACKOFF", 500*time.Millisecond),
+		ConnectMaxElapsed: env.Get(p, "PG_CONNECT_MAX_ELAPSED", 30*time.Second),
+		ConnectJitter:     0.2,
 	}
+	if err := p.Err(); err != nil {
+		log.Printf("Invalid database pool settings, falling back to defaults: %v", err)
+	}
+	return config
 }
 
 // ConnectionString returns the appropriate connection string for the environment
@@ -127,19 +182,43 @@ func (c DatabaseConfig) ConnectionString() string {
 		// For production with IAM authentication
 		return fmt.Sprintf(
 			"host=%s port=%s user=%s dbname=%s sslmode=%s",
-			c.Host, c.Port, c.User, c.DBName, c.SSLMode,
-		)
+			quote(c.Host), quote(c.Port), quote(c.User), quote(c.DBName), quote(c.SSLMode),
+		) + c.tlsParams()
 	}
 
 	// For local/development with password authentication
-	base := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		c.Host, c.Port, c.User, c.Password, c.DBName, c.SSLMode,
-	)
+	base := fmt.Sprintf("host=%s port=%s user=%s", quote(c.Host), quote(c.Port), quote(c.User))
+	if c.Password != "" {
+		// An empty value would swallow the next parameter, and a
+		// CredentialProvider supplies the password at connect time anyway
+		base += fmt.Sprintf(" password=%s", quote(c.Password))
+	}
+	base += fmt.Sprintf(" dbname=%s sslmode=%s", quote(c.DBName), quote(c.SSLMode)) + c.tlsParams()
 
 	if c.Schema != "" {
-		base += fmt.Sprintf(" search_path=%s", c.Schema)
+		base += fmt.Sprintf(" search_path=%s", quote(c.Schema))
 	}
 
 	return base
 }
+
+// quote renders v as a single-quoted connection string value, so spaces,
+// quotes and backslashes (e.g. in decoded URL passwords) survive parsing
+func quote(v string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+}
+
+// tlsParams renders the certificate settings for ConnectionString
+func (c DatabaseConfig) tlsParams() string {
+	var params string
+	if c.SSLRootCert != "" {
+		params += fmt.Sprintf(" sslrootcert=%s", quote(c.SSLRootCert))
+	}
+	if c.SSLCert != "" {
+		params += fmt.Sprintf(" sslcert=%s", quote(c.SSLCert))
+	}
+	if c.SSLKey != "" {
+		params += fmt.Sprintf(" sslkey=%s", quote(c.SSLKey))
+	}
+	return params
+}
@@ -0,0 +1,60 @@
+package config
+
+import "testing"
+
+func TestConnectionString(t *testing.T) {
+	base := DatabaseConfig{
+		Host: "db.internal", Port: "5432", User: "app", Password: "secret",
+		DBName: "ranor", SSLMode: "require",
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*DatabaseConfig)
+		want   string
+	}{
+		{
+			"password auth",
+			func(c *DatabaseConfig) {},
+			"host='db.internal' port='5432' user='app' password='secret' dbname='ranor' sslmode='require'",
+		},
+		{
+			"quotes and backslashes escaped",
+			func(c *DatabaseConfig) { c.Password = `it's a \pass word` },
+			`host='db.internal' port='5432' user='app' password='it\'s a \\pass word' dbname='ranor' sslmode='require'`,
+		},
+		{
+			"empty password omitted",
+			func(c *DatabaseConfig) { c.Password = "" },
+			"host='db.internal' port='5432' user='app' dbname='ranor' sslmode='require'",
+		},
+		{
+			"schema",
+			func(c *DatabaseConfig) { c.Schema = "billing" },
+			"host='db.internal' port='5432' user='app' password='secret' dbname='ranor' sslmode='require' search_path='billing'",
+		},
+		{
+			"tls files",
+			func(c *DatabaseConfig) {
+				c.SSLMode, c.SSLRootCert, c.SSLCert, c.SSLKey = "verify-full", "/certs/ca.pem", "/certs/my cert.pem", "/certs/key.pem"
+			},
+			"host='db.internal' port='5432' user='app' password='secret' dbname='ranor' sslmode='verify-full'" +
+				" sslrootcert='/certs/ca.pem' sslcert='/certs/my cert.pem' sslkey='/certs/key.pem'",
+		},
+		{
+			"iam auth leaves out password and schema",
+			func(c *DatabaseConfig) { c.UseIAMAuth, c.Schema = true, "billing" },
+			"host='db.internal' port='5432' user='app' dbname='ranor' sslmode='require'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := base
+			tt.modify(&c)
+			if got := c.ConnectionString(); got != tt.want {
+				t.Errorf("ConnectionString =\n  %s\nwant\n  %s", got, tt.want)
+			}
+		})
+	}
+}
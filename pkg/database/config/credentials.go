@@ -0,0 +1,145 @@
+package config
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CredentialProvider resolves the database password at connect time.
+// Invalidate is called when the database rejects the password, so the next
+// Password call fetches a fresh (rotated) secret.
+type CredentialProvider interface {
+	Password(ctx context.Context) (string, error)
+	Invalidate()
+}
+
+// CachedProvider caches a provider's password for a TTL
+type CachedProvider struct {
+	fetch func(ctx context.Context) (string, error)
+	ttl   time.Duration
+
+	mu       sync.Mutex
+	password string
+	expires  time.Time
+}
+
+func NewCachedProvider(ttl time.Duration, fetch func(ctx context.Context) (string, error)) *CachedProvider {
+	return &CachedProvider{fetch: fetch, ttl: ttl}
+}
+
+func (p *CachedProvider) Password(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.password != "" && time.Now().Before(p.expires) {
+		return p.password, nil
+	}
+
+	password, err := p.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	p.password = password
+	p.expires = time.Now().Add(p.ttl)
+	return password, nil
+}
+
+func (p *CachedProvider) Invalidate() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.password = ""
+}
+
+// NewSecretManagerProvider reads the latest version of a GCP Secret Manager
+// secret using the instance's default service account from the metadata
+// server (Cloud Run, GKE, GCE)
+func NewSecretManagerProvider(project, secret string, ttl time.Duration) *CachedProvider {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	return NewCachedProvider(ttl, func(ctx context.Context) (string, error) {
+		token, err := metadataToken(ctx, client)
+		if err != nil {
+			return "", err
+		}
+
+		url := fmt.Sprintf("https://secretmanager.googleapis.com/v1/projects/%s/secrets/%s/versions/latest:access", project, secret)
+		var resp struct {
+			Payload struct {
+				Data string `json:"data"`
+			} `json:"payload"`
+		}
+		if err := getJSON(ctx, client, url, map[string]string{"Authorization": "Bearer " + token}, &resp); err != nil {
+			return "", fmt.Errorf("error accessing secret %s: %w", secret, err)
+		}
+
+		data, err := base64.StdEncoding.DecodeString(resp.Payload.Data)
+		if err != nil {
+			return "", fmt.Errorf("error decoding secret %s: %w", secret, err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	})
+}
+
+func metadataToken(ctx context.Context, client *http.Client) (string, error) {
+	var resp struct {
+		AccessToken string `json:"access_token"`
+	}
+	err := getJSON(ctx, client,
+		"http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token",
+		map[string]string{"Metadata-Flavor": "Google"}, &resp)
+	if err != nil {
+		return "", fmt.Errorf("error fetching metadata token: %w", err)
+	}
+	return resp.AccessToken, nil
+}
+
+// NewVaultProvider reads a key from a HashiCorp Vault KV v2 secret, e.g.
+// path "secret/data/billing/db" and key "password"
+func NewVaultProvider(addr, token, path, key string, ttl time.Duration) *CachedProvider {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	return NewCachedProvider(ttl, func(ctx context.Context) (string, error) {
+		var resp struct {
+			Data struct {
+				Data map[string]any `json:"data"`
+			} `json:"data"`
+		}
+		url := strings.TrimRight(addr, "/") + "/v1/" + strings.TrimLeft(path, "/")
+		if err := getJSON(ctx, client, url, map[string]string{"X-Vault-Token": token}, &resp); err != nil {
+			return "", fmt.Errorf("error reading vault secret %s: %w", path, err)
+		}
+
+		value, ok := resp.Data.Data[key].(string)
+		if !ok {
+			return "", fmt.Errorf("vault secret %s has no string key %q", path, key)
+		}
+		return value, nil
+	})
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,69 @@
+package config
+
+import "time"
+
+// Option overrides a default chosen by NewDatabaseConfig. Options are applied
+// after ENV and DATABASE_URL, so they take precedence over both.
+type Option func(*DatabaseConfig)
+
+func WithHost(host string) Option {
+	return func(c *DatabaseConfig) { c.Host = host }
+}
+
+func WithPort(port string) Option {
+	return func(c *DatabaseConfig) { c.Port = port }
+}
+
+func WithUser(user string) Option {
+	return func(c *DatabaseConfig) { c.User = user }
+}
+
+// WithPassword sets a static password and switches to password authentication
+func WithPassword(password string) Option {
+	return func(c *DatabaseConfig) {
+		c.Password = password
+		c.UseIAMAuth = false
+	}
+}
+
+func WithDBName(name string) Option {
+	return func(c *DatabaseConfig) { c.DBName = name }
+}
+
+func WithSchema(schema string) Option {
+	return func(c *DatabaseConfig) { c.Schema = schema }
+}
+
+func WithSSLMode(mode string) Option {
+	return func(c *DatabaseConfig) { c.SSLMode = mode }
+}
+
+// WithTLS verifies the server against rootCert (system roots when empty) and
+// presents cert and key, when set, as the client certificate
+func WithTLS(mode, rootCert, cert, key string) Option {
+	return func(c *DatabaseConfig) {
+		c.SSLMode = mode
+		c.SSLRootCert = rootCert
+		c.SSLCert = cert
+		c.SSLKey = key
+	}
+}
+
+func WithPoolSize(minConns, maxConns int32) Option {
+	return func(c *DatabaseConfig) {
+		c.MinConns = minConns
+		c.MaxConns = maxConns
+	}
+}
+
+func WithStatementTimeout(d time.Duration) Option {
+	return func(c *DatabaseConfig) { c.StatementTimeout = d }
+}
+
+func WithCredentials(provider CredentialProvider) Option {
+	return func(c *DatabaseConfig) { c.Credentials = provider }
+}
+
+func WithReplicas(dsns ...string) Option {
+	return func(c *DatabaseConfig) { c.ReplicaDSNs = dsns }
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// FromURL builds a config from a postgres:// or postgresql:// URL. Pool,
+// cache, timeout and retry settings use the same defaults as
+// NewDatabaseConfig; sslmode defaults to require unless the URL sets it. The
+// result is validated before it is returned.
+func FromURL(rawURL string) (*DatabaseConfig, error) {
+	config := defaults()
+	config.SSLMode = "require"
+	if err := config.ApplyURL(rawURL); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// ApplyURL overrides the connection fields present in a postgres URL. The
+// sslmode, sslrootcert, sslcert, sslkey and search_path (or schema) query
+// parameters are honored.
+func (c *DatabaseConfig) ApplyURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("error parsing database URL: %w", err)
+	}
+	if u.Scheme != "postgres" && u.Scheme != "postgresql" {
+		return fmt.Errorf("unsupported database URL scheme %q", u.Scheme)
+	}
+
+	if host := u.Hostname(); host != "" {
+		c.Host = host
+	}
+	if port := u.Port(); port != "" {
+		c.Port = port
+	}
+	if u.User != nil {
+		c.User = u.User.Username()
+		if password, ok := u.User.Password(); ok {
+			c.Password = password
+			c.UseIAMAuth = false // An explicit password means password auth
+		}
+	}
+	if dbName := strings.TrimPrefix(u.Path, "/"); dbName != "" {
+		c.DBName = dbName
+	}
+
+	query := u.Query()
+	if sslMode := query.Get("sslmode"); sslMode != "" {
+		c.SSLMode = sslMode
+	}
+	if rootCert := query.Get("sslrootcert"); rootCert != "" {
+		c.SSLRootCert = rootCert
+	}
+	if cert := query.Get("sslcert"); cert != "" {
+		c.SSLCert = cert
+	}
+	if key := query.Get("sslkey"); key != "" {
+		c.SSLKey = key
+	}
+	if schema := query.Get("search_path"); schema != "" {
+		c.Schema = schema
+	} else if schema := query.Get("schema"); schema != "" {
+		c.Schema = schema
+	}
+
+	return nil
+}
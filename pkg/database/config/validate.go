@@ -0,0 +1,72 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnknownEnvironment  = errors.New("unknown environment")
+	ErrMissingInstanceName = errors.New("missing INSTANCE_CONNECTION_NAME")
+	ErrEmptyPassword       = errors.New("empty database password")
+	ErrInvalidPoolSize     = errors.New("invalid pool size")
+	ErrInvalidTLS          = errors.New("invalid TLS settings")
+	ErrInvalidCacheMode    = errors.New("invalid statement cache mode")
+)
+
+// statementCacheModes are the accepted StatementCacheMode values
+var statementCacheModes = map[string]bool{
+	"": true, "cache_statement": true, "cache_describe": true,
+	"describe_exec": true, "exec": true, "simple_protocol": true,
+}
+
+// sslModes are the sslmode values pgx accepts
+var sslModes = map[string]bool{
+	"": true, "disable": true, "allow": true, "prefer": true,
+	"require": true, "verify-ca": true, "verify-full": true,
+}
+
+// Validate reports every problem with the config, joined into one error.
+// Match individual problems with errors.Is against the Err* values.
+func (c *DatabaseConfig) Validate() error {
+	var errs []error
+
+	switch c.Environment {
+	case "", Local, Development, Production:
+	default:
+		errs = append(errs, fmt.Errorf("%w: %q", ErrUnknownEnvironment, c.Environment))
+	}
+
+	// Cloud SQL environments reach the database through the proxy on
+	// localhost, which needs to know the instance
+	if (c.Environment == Development || c.Environment == Production) && c.Host == "localhost" && c.InstanceName == "" {
+		errs = append(errs, ErrMissingInstanceName)
+	}
+
+	if !c.UseIAMAuth && c.Credentials == nil && c.Password == "" {
+		errs = append(errs, fmt.Errorf("%w for user %q", ErrEmptyPassword, c.User))
+	}
+
+	if c.MaxConns < 1 {
+		errs = append(errs, fmt.Errorf("%w: MaxConns %d must be at least 1", ErrInvalidPoolSize, c.MaxConns))
+	}
+	if c.MinConns < 0 || c.MinConns > c.MaxConns {
+		errs = append(errs, fmt.Errorf("%w: MinConns %d must be between 0 and MaxConns %d", ErrInvalidPoolSize, c.MinConns, c.MaxConns))
+	}
+
+	if !statementCacheModes[c.StatementCacheMode] {
+		errs = append(errs, fmt.Errorf("%w: %q", ErrInvalidCacheMode, c.StatementCacheMode))
+	}
+
+	if !sslModes[c.SSLMode] {
+		errs = append(errs, fmt.Errorf("%w: unknown sslmode %q", ErrInvalidTLS, c.SSLMode))
+	}
+	if (c.SSLCert == "") != (c.SSLKey == "") {
+		errs = append(errs, fmt.Errorf("%w: SSLCert and SSLKey must be set together", ErrInvalidTLS))
+	}
+	if c.SSLMode == "disable" && (c.SSLRootCert != "" || c.SSLCert != "") {
+		errs = append(errs, fmt.Errorf("%w: certificates configured with sslmode=disable", ErrInvalidTLS))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func validConfig() DatabaseConfig {
+	return DatabaseConfig{
+		Environment: Local, Host: "localhost", Port: "5432", User: "postgres", Password: "postgres",
+		DBName: "ranor", SSLMode: "disable", MaxConns: 10, MinConns: 2, StatementCacheMode: "cache_statement",
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*DatabaseConfig)
+		want   []error
+	}{
+		{"valid", func(c *DatabaseConfig) {}, nil},
+		{"unknown environment", func(c *DatabaseConfig) { c.Environment = "staging" }, []error{ErrUnknownEnvironment}},
+		{"cloud sql without instance", func(c *DatabaseConfig) { c.Environment = Production }, []error{ErrMissingInstanceName}},
+		{"cloud sql with instance", func(c *DatabaseConfig) { c.Environment, c.InstanceName = Development, "p:r:i" }, nil},
+		{"empty password", func(c *DatabaseConfig) { c.Password = "" }, []error{ErrEmptyPassword}},
+		{"iam auth without password", func(c *DatabaseConfig) { c.Password, c.UseIAMAuth = "", true }, nil},
+		{"no connections", func(c *DatabaseConfig) { c.MaxConns, c.MinConns = 0, 0 }, []error{ErrInvalidPoolSize}},
+		{"min above max", func(c *DatabaseConfig) { c.MinConns = 20 }, []error{ErrInvalidPoolSize}},
+		{"unknown cache mode", func(c *DatabaseConfig) { c.StatementCacheMode = "always" }, []error{ErrInvalidCacheMode}},
+		{"unknown sslmode", func(c *DatabaseConfig) { c.SSLMode = "strict" }, []error{ErrInvalidTLS}},
+		{"cert without key", func(c *DatabaseConfig) { c.SSLMode, c.SSLCert = "require", "cert.pem" }, []error{ErrInvalidTLS}},
+		{"certs with sslmode disable", func(c *DatabaseConfig) { c.SSLRootCert = "ca.pem" }, []error{ErrInvalidTLS}},
+		{
+			"every problem reported",
+			func(c *DatabaseConfig) { c.Password, c.MaxConns, c.SSLMode = "", 0, "strict" },
+			[]error{ErrEmptyPassword, ErrInvalidPoolSize, ErrInvalidTLS},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig()
+			tt.modify(&c)
+
+			err := c.Validate()
+			if tt.want == nil && err != nil {
+				t.Fatalf("Validate = %v, want nil", err)
+			}
+			for _, want := range tt.want {
+				if !errors.Is(err, want) {
+					t.Errorf("Validate = %v, want %v", err, want)
+				}
+			}
+		})
+	}
+}
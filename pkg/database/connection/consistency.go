@@ -0,0 +1,82 @@
+package connection
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ConsistencyToken carries the primary's WAL position after a write so later
+// reads can be served by a replica only once it has replayed that far.
+type ConsistencyToken string
+
+// CaptureToken returns a token for the primary's current WAL insert position.
+// Call it after the write has committed.
+func CaptureToken(ctx context.Context, primary *pgxpool.Pool) (ConsistencyToken, error) {
+	var lsn string
+	if err := primary.QueryRow(ctx, "SELECT pg_current_wal_lsn()::text").Scan(&lsn); err != nil {
+		return "", fmt.Errorf("error reading primary LSN: %w", err)
+	}
+	return ConsistencyToken(base64.RawURLEncoding.EncodeToString([]byte(lsn))), nil
+}
+
+// LSN decodes the WAL position held by the token
+func (t ConsistencyToken) LSN() (string, error) {
+	lsn, err := base64.RawURLEncoding.DecodeString(string(t))
+	if err != nil {
+		return "", fmt.Errorf("invalid consistency token: %w", err)
+	}
+	return string(lsn), nil
+}
+
+// ReplicaCaughtUp reports whether the replica has replayed past the token's LSN
+func ReplicaCaughtUp(ctx context.Context, replica *pgxpool.Pool, token ConsistencyToken) (bool, error) {
+	lsn, err := token.LSN()
+	if err != nil {
+		return false, err
+	}
+
+	var caughtUp bool
+	err = replica.QueryRow(ctx,
+		"SELECT COALESCE(pg_last_wal_replay_lsn() >= $1::pg_lsn, false)", lsn,
+	).Scan(&caughtUp)
+	if err != nil {
+		return false, fmt.Errorf("error reading replica LSN: %w", err)
+	}
+	return caughtUp, nil
+}
+
+// PoolForRead waits up to maxWait for the replica to catch up with the token
+// and returns it, falling back to the primary otherwise. An empty token means
+// the caller has no pending writes and the replica is returned immediately.
+func PoolForRead(ctx context.Context, primary, replica *pgxpool.Pool, token ConsistencyToken, maxWait time.Duration) *pgxpool.Pool {
+	if replica == nil {
+		return primary
+	}
+	if token == "" {
+		return replica
+	}
+
+	deadline := time.Now().Add(maxWait)
+	for {
+		caughtUp, err := ReplicaCaughtUp(ctx, replica, token)
+		if err != nil {
+			return primary
+		}
+		if caughtUp {
+			return replica
+		}
+		if time.Now().After(deadline) {
+			return primary
+		}
+
+		select {
+		case <-ctx.Done():
+			return primary
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Step is one database's share of a coordinated write.
+//
+// Coordinate is NOT two-phase commit. Every step's writes run in its own open
+// transaction first (prepare), then the transactions are committed one by
+// one (confirm). If a commit fails after earlier steps have committed, those
+// steps can only be undone by their Compensate functions, which run in
+// reverse order and may themselves fail. Between the first and last commit
+// other readers can observe a partial result. Use it to replace unsafe
+// fire-and-forget dual writes, not where atomicity is required.
+type Step struct {
+	Name    string
+	DB      Database
+	Prepare func(ctx context.Context, tx pgx.Tx) error
+
+	// Compensate reverses this step's committed writes. It may be nil for
+	// steps whose writes are harmless to leave behind (e.g. audit records).
+	Compensate func(ctx context.Context) error
+}
+
+// CoordinationError reports which steps committed and whether compensation
+// succeeded when a coordinated write fails part way through
+type CoordinationError struct {
+	Step            string   // Step that failed
+	Err             error    // Failure of that step
+	Committed       []string // Steps that had committed before the failure
+	CompensationErr error    // Errors from compensating the committed steps
+}
+
+func (e *CoordinationError) Error() string {
+	msg := fmt.Sprintf("coordinated write failed at %s: %v", e.Step, e.Err)
+	if len(e.Committed) > 0 {
+		msg += fmt.Sprintf(" (compensated %v", e.Committed)
+		if e.CompensationErr != nil {
+			msg += fmt.Sprintf(", compensation failed: %v", e.CompensationErr)
+		}
+		msg += ")"
+	}
+	return msg
+}
+
+func (e *CoordinationError) Unwrap() error {
+	return e.Err
+}
+
+// Coordinate prepares every step, then commits them in order, compensating
+// already committed steps if a later commit fails. See Step for the
+// consistency guarantees (and lack thereof).
+func Coordinate(ctx context.Context, steps ...Step) error {
+	txs := make([]pgx.Tx, 0, len(steps))
+	rollback := func() {
+		for _, tx := range txs {
+			_ = tx.Rollback(ctx)
+		}
+	}
+
+	// Prepare: do all the work while nothing is committed yet
+	for _, step := range steps {
+		tx, err := step.DB.GetWritePool().Begin(ctx)
+		if err != nil {
+			rollback()
+			return &CoordinationError{Step: step.Name, Err: fmt.Errorf("error starting transaction: %w", err)}
+		}
+		txs = append(txs, tx)
+
+		if err := ApplySession(ctx, tx); err != nil {
+			rollback()
+			return &CoordinationError{Step: step.Name, Err: err}
+		}
+
+		if err := step.Prepare(ctx, tx); err != nil {
+			rollback()
+			return &CoordinationError{Step: step.Name, Err: err}
+		}
+	}
+
+	// Confirm: commit in order, compensating on the first failure
+	for i, tx := range txs {
+		if err := tx.Commit(ctx); err != nil {
+			for _, pending := range txs[i+1:] {
+				_ = pending.Rollback(ctx)
+			}
+
+			cerr := &CoordinationError{Step: steps[i].Name, Err: fmt.Errorf("error committing: %w", err)}
+			var compErrs []error
+			for j := i - 1; j >= 0; j-- {
+				cerr.Committed = append(cerr.Committed, steps[j].Name)
+				if steps[j].Compensate == nil {
+					continue
+				}
+				if err := steps[j].Compensate(ctx); err != nil {
+					compErrs = append(compErrs, fmt.Errorf("%s: %w", steps[j].Name, err))
+				}
+			}
+			cerr.CompensationErr = errors.Join(compErrs...)
+			return cerr
+		}
+	}
+
+	return nil
+}
@@ -2,24 +2,118 @@ package connection
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/ranson21/ranor-common/pkg/database/config"
 )
 
+// replicaCheckInterval is how often replica health is re-evaluated
+const replicaCheckInterval = 10 * time.Second
+
 type Database interface {
 	Ping(ctx context.Context) error
 	Close()
 	GetPool() *pgxpool.Pool
+	GetWritePool() *pgxpool.Pool
+	GetReadPool() *pgxpool.Pool
+	GetSQLDB() *sql.DB
 }
 
 type DB struct {
-	pool *pgxpool.Pool
+	pool     *pgxpool.Pool
+	replicas []*replica
+	next     atomic.Uint64
+	stop     chan struct{}
+	wg       sync.WaitGroup
+
+	sqlOnce sync.Once
+	sqlDB   *sql.DB
+}
+
+type replica struct {
+	pool    *pgxpool.Pool
+	healthy atomic.Bool
+}
+
+func NewDB(cfg *config.DatabaseConfig, opts ...Option) (Database, error) {
+	return NewDBWithContext(context.Background(), cfg, opts...)
+}
+
+// NewDBWithContext connects like NewDB, retrying per the config's Connect
+// settings and stopping early if ctx is cancelled
+func NewDBWithContext(ctx context.Context, cfg *config.DatabaseConfig, opts ...Option) (Database, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	pool, err := connectWithRetry(ctx, cfg, o, cfg.ConnectionString())
+	if err != nil {
+		return nil, err
+	}
+
+	db := &DB{pool: pool, stop: make(chan struct{})}
+
+	for i, dsn := range cfg.ReplicaDSNs {
+		replicaPool, err := openPool(ctx, cfg, o, dsn, nil)
+		if err != nil {
+			db.Close() // Clean up the pools opened so far
+			return nil, fmt.Errorf("error configuring replica: %w", err)
+		}
+
+		// An unreachable replica must not block startup: reads fall back to
+		// the primary until monitorReplicas sees it recover
+		r := &replica{pool: replicaPool}
+		pingCtx, cancel := context.WithTimeout(ctx, replicaCheckInterval/2)
+		if err := replicaPool.Ping(pingCtx); err != nil {
+			log.Printf("Replica %d unreachable, reading from primary until it recovers: %v", i, err)
+		} else {
+			r.healthy.Store(true)
+		}
+		cancel()
+		db.replicas = append(db.replicas, r)
+	}
+
+	if len(db.replicas) > 0 {
+		db.wg.Add(1)
+		go db.monitorReplicas()
+	}
+
+	return db, nil
+}
+
+// newPool opens a pool and checks that it can connect
+func newPool(ctx context.Context, cfg *config.DatabaseConfig, o *options, connString string) (*pgxpool.Pool, error) {
+	pool, err := openPool(ctx, cfg, o, connString, cfg.Credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	// Test the connection
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close() // Clean up if connection test fails
+		return nil, fmt.Errorf("error connecting to database: %w", err)
+	}
+
+	return pool, nil
 }
 
-func NewDB(cfg *config.DatabaseConfig) (Database, error) {
-	poolConfig, err := pgxpool.ParseConfig(cfg.ConnectionString())
+// openPool configures a pool without connecting; connections are opened on
+// demand. credentials, when set, supplies the password for every connection;
+// replicas pass nil and keep the credentials in their own DSN.
+func openPool(ctx context.Context, cfg *config.DatabaseConfig, o *options, connString string, credentials config.CredentialProvider) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(connString)
 	if err != nil {
 		return nil, fmt.Errorf("error parsing connection string: %w", err)
 	}
@@ -30,18 +124,59 @@ func NewDB(cfg *config.DatabaseConfig) (Database, error) {
 	poolConfig.MaxConnIdleTime = cfg.MaxIdleTime
 	poolConfig.MaxConnLifetime = cfg.MaxLifetime
 
-	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
-	if err != nil {
-		return nil, fmt.Errorf("error creating connection pool: %w", err)
+	if mode, ok := queryExecModes[cfg.StatementCacheMode]; ok {
+		poolConfig.ConnConfig.DefaultQueryExecMode = mode
+	}
+	if cfg.StatementCacheCapacity > 0 {
+		poolConfig.ConnConfig.StatementCacheCapacity = cfg.StatementCacheCapacity
+		poolConfig.ConnConfig.DescriptionCacheCapacity = cfg.StatementCacheCapacity
 	}
 
-	// Test the connection
-	if err := pool.Ping(context.Background()); err != nil {
-		pool.Close() // Clean up if connection test fails
-		return nil, fmt.Errorf("error connecting to database: %w", err)
+	if cfg.ConnectTimeout > 0 {
+		poolConfig.ConnConfig.ConnectTimeout = cfg.ConnectTimeout
+	}
+	if settings := sessionTimeouts(cfg); len(settings) > 0 {
+		// Applied with SET rather than startup parameters, which poolers and
+		// the Cloud SQL proxy may not forward
+		poolConfig.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+			for _, stmt := range settings {
+				if _, err := conn.Exec(ctx, stmt); err != nil {
+					return fmt.Errorf("error applying session timeout: %w", err)
+				}
+			}
+			return nil
+		}
+	}
+
+	tracers := o.tracers
+	if o.stmtStats != nil && cachesStatements(cfg.StatementCacheMode) {
+		tracers = append(slices.Clip(tracers), &stmtCacheTracer{stats: o.stmtStats})
+	}
+	if credentials != nil {
+		// Drop the cached secret whenever a connection fails to authenticate,
+		// so new connections pick up a rotated one without waiting for the TTL
+		tracers = append(slices.Clip(tracers), &credentialTracer{credentials: credentials})
+	}
+	if tracer := combineTracers(tracers); tracer != nil {
+		poolConfig.ConnConfig.Tracer = tracer
 	}
 
-	return &DB{pool: pool}, nil
+	if credentials != nil {
+		poolConfig.BeforeConnect = func(ctx context.Context, connConfig *pgx.ConnConfig) error {
+			password, err := credentials.Password(ctx)
+			if err != nil {
+				return fmt.Errorf("error resolving database password: %w", err)
+			}
+			connConfig.Password = password
+			return nil
+		}
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error creating connection pool: %w", err)
+	}
+	return pool, nil
 }
 
 // Implement Database interface methods
@@ -50,6 +185,17 @@ func (db *DB) Ping(ctx context.Context) error {
 }
 
 func (db *DB) Close() {
+	if db.stop != nil {
+		close(db.stop)
+		db.wg.Wait()
+		db.stop = nil
+	}
+	if db.sqlDB != nil {
+		db.sqlDB.Close()
+	}
+	for _, r := range db.replicas {
+		r.pool.Close()
+	}
 	if db.pool != nil {
 		db.pool.Close()
 	}
@@ -58,3 +204,94 @@ func (db *DB) Close() {
 func (db *DB) GetPool() *pgxpool.Pool {
 	return db.pool
 }
+
+// GetSQLDB returns a database/sql handle backed by the primary pool, for
+// libraries that expect database/sql (sqlx, migration tools). It shares the
+// pool's connections and settings instead of opening its own.
+func (db *DB) GetSQLDB() *sql.DB {
+	db.sqlOnce.Do(func() {
+		db.sqlDB = stdlib.OpenDBFromPool(db.pool)
+	})
+	return db.sqlDB
+}
+
+// GetWritePool returns the primary pool
+func (db *DB) GetWritePool() *pgxpool.Pool {
+	return db.pool
+}
+
+// GetReadPool returns the next healthy replica in round-robin order, or the
+// primary when no replica is configured or healthy
+func (db *DB) GetReadPool() *pgxpool.Pool {
+	n := len(db.replicas)
+	if n == 0 {
+		return db.pool
+	}
+
+	start := db.next.Add(1)
+	for i := 0; i < n; i++ {
+		r := db.replicas[(start+uint64(i))%uint64(n)]
+		if r.healthy.Load() {
+			return r.pool
+		}
+	}
+	return db.pool
+}
+
+func (db *DB) monitorReplicas() {
+	defer db.wg.Done()
+
+	ticker := time.NewTicker(replicaCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-db.stop:
+			return
+		case <-ticker.C:
+			for _, r := range db.replicas {
+				ctx, cancel := context.WithTimeout(context.Background(), replicaCheckInterval/2)
+				r.healthy.Store(r.pool.Ping(ctx) == nil)
+				cancel()
+			}
+		}
+	}
+}
+
+// sessionTimeouts returns the SET statements for the configured timeouts
+func sessionTimeouts(cfg *config.DatabaseConfig) []string {
+	var stmts []string
+	if cfg.StatementTimeout > 0 {
+		stmts = append(stmts, fmt.Sprintf("SET statement_timeout = %d", cfg.StatementTimeout.Milliseconds()))
+	}
+	if cfg.IdleInTransactionTimeout > 0 {
+		stmts = append(stmts, fmt.Sprintf("SET idle_in_transaction_session_timeout = %d", cfg.IdleInTransactionTimeout.Milliseconds()))
+	}
+	return stmts
+}
+
+// credentialTracer invalidates cached credentials on authentication failures
+type credentialTracer struct {
+	credentials config.CredentialProvider
+}
+
+func (t *credentialTracer) TraceConnectStart(ctx context.Context, _ pgx.TraceConnectStartData) context.Context {
+	return ctx
+}
+
+func (t *credentialTracer) TraceConnectEnd(_ context.Context, data pgx.TraceConnectEndData) {
+	if data.Err != nil && isAuthFailure(data.Err) {
+		t.credentials.Invalidate()
+	}
+}
+
+func (t *credentialTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryStartData) context.Context {
+	return ctx
+}
+
+func (t *credentialTracer) TraceQueryEnd(context.Context, *pgx.Conn, pgx.TraceQueryEndData) {}
+
+func isAuthFailure(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && (pgErr.Code == "28P01" || pgErr.Code == "28000")
+}
@@ -0,0 +1,118 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/sync/semaphore"
+)
+
+// FairPool caps the number of connections each caller (component or tenant)
+// may hold at once from a shared pool, so a single busy caller can't starve
+// the rest of the service.
+type FairPool struct {
+	pool         *pgxpool.Pool
+	defaultLimit int64
+
+	mu      sync.Mutex
+	limits  map[string]*slot // Configured callers, kept for the pool's lifetime
+	dynamic map[string]*slot // Other callers, dropped once they hold no slot
+}
+
+// slot is a caller's semaphore and the number of Acquire calls using it
+type slot struct {
+	sem   *semaphore.Weighted
+	users int
+}
+
+// Conn is a pooled connection acquired through a FairPool. Release must be
+// called instead of the embedded pgxpool.Conn's Release.
+type Conn struct {
+	*pgxpool.Conn
+	fp     *FairPool
+	caller string
+	slot   *slot
+	once   sync.Once
+}
+
+// NewFairPool wraps pool with per-caller limits. Callers without an explicit
+// entry in limits share the same defaultLimit, each with its own semaphore.
+// Every limit must be at least 1.
+func NewFairPool(pool *pgxpool.Pool, limits map[string]int64, defaultLimit int64) (*FairPool, error) {
+	if defaultLimit < 1 {
+		return nil, fmt.Errorf("invalid default connection limit %d", defaultLimit)
+	}
+
+	fp := &FairPool{
+		pool:         pool,
+		defaultLimit: defaultLimit,
+		limits:       make(map[string]*slot),
+		dynamic:      make(map[string]*slot),
+	}
+	for caller, limit := range limits {
+		if limit < 1 {
+			return nil, fmt.Errorf("invalid connection limit %d for %s", limit, caller)
+		}
+		fp.limits[caller] = &slot{sem: semaphore.NewWeighted(limit)}
+	}
+	return fp, nil
+}
+
+// Acquire waits for a free slot for caller, then acquires a connection from
+// the underlying pool.
+func (p *FairPool) Acquire(ctx context.Context, caller string) (*Conn, error) {
+	s := p.claim(caller)
+	if err := s.sem.Acquire(ctx, 1); err != nil {
+		p.unclaim(caller, s)
+		return nil, fmt.Errorf("error waiting for connection slot for %s: %w", caller, err)
+	}
+
+	conn, err := p.pool.Acquire(ctx)
+	if err != nil {
+		s.sem.Release(1)
+		p.unclaim(caller, s)
+		return nil, fmt.Errorf("error acquiring connection for %s: %w", caller, err)
+	}
+
+	return &Conn{Conn: conn, fp: p, caller: caller, slot: s}, nil
+}
+
+// Release returns the connection to the pool and frees the caller's slot
+func (c *Conn) Release() {
+	c.once.Do(func() {
+		c.Conn.Release()
+		c.slot.sem.Release(1)
+		c.fp.unclaim(c.caller, c.slot)
+	})
+}
+
+// claim returns the caller's slot, creating one for unconfigured callers
+func (p *FairPool) claim(caller string) *slot {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s, exists := p.limits[caller]
+	if !exists {
+		s, exists = p.dynamic[caller]
+		if !exists {
+			s = &slot{sem: semaphore.NewWeighted(p.defaultLimit)}
+			p.dynamic[caller] = s
+		}
+	}
+	s.users++
+	return s
+}
+
+// unclaim drops an unconfigured caller's slot once nothing uses it, so
+// per-tenant callers don't accumulate
+func (p *FairPool) unclaim(caller string, s *slot) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s.users--
+	if s.users == 0 && p.dynamic[caller] == s {
+		delete(p.dynamic, caller)
+	}
+}
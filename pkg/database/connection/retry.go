@@ -0,0 +1,60 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ranson21/ranor-common/pkg/database/config"
+)
+
+// connectWithRetry opens a pool, retrying with exponential backoff until it
+// succeeds, the attempts or elapsed time run out, or ctx is cancelled
+func connectWithRetry(ctx context.Context, cfg *config.DatabaseConfig, o *options, connString string) (*pgxpool.Pool, error) {
+	attempts := cfg.ConnectAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	start := time.Now()
+	delay := cfg.ConnectBackoff
+
+	for attempt := 1; ; attempt++ {
+		pool, err := newPool(ctx, cfg, o, connString)
+		if err == nil {
+			if attempt > 1 {
+				log.Printf("Connected to database on attempt %d after %v", attempt, time.Since(start).Round(time.Millisecond))
+			}
+			return pool, nil
+		}
+
+		if attempt >= attempts {
+			return nil, fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+
+		wait := jitter(delay, cfg.ConnectJitter)
+		if cfg.ConnectMaxElapsed > 0 && time.Since(start)+wait > cfg.ConnectMaxElapsed {
+			return nil, fmt.Errorf("giving up after %d attempts in %v: %w", attempt, time.Since(start).Round(time.Millisecond), err)
+		}
+
+		log.Printf("Database connection attempt %d/%d failed, retrying in %v: %v", attempt, attempts, wait.Round(time.Millisecond), err)
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("connection cancelled: %w", ctx.Err())
+		case <-time.After(wait):
+		}
+		delay *= 2
+	}
+}
+
+func jitter(d time.Duration, fraction float64) time.Duration {
+	if fraction <= 0 || d <= 0 {
+		return d
+	}
+	delta := (rand.Float64()*2 - 1) * fraction * float64(d)
+	return d + time.Duration(delta)
+}
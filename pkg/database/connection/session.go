@@ -0,0 +1,80 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SessionSettings are Postgres settings (GUCs) applied with SET LOCAL to every
+// transaction started through WithTx, InSession or Coordinate for a request,
+// e.g. the tenant used by row level security policies.
+//
+// Queries issued directly on GetPool, GetReadPool or GetSQLDB do NOT get the
+// settings. Code relying on them for row level security must go through one
+// of the helpers above.
+type SessionSettings map[string]string
+
+type sessionSettingsKey struct{}
+
+// WithSessionSettings returns a context carrying settings merged over any
+// already present
+func WithSessionSettings(ctx context.Context, settings SessionSettings) context.Context {
+	merged := maps.Clone(SessionSettingsFromContext(ctx))
+	if merged == nil {
+		merged = make(SessionSettings, len(settings))
+	}
+	maps.Copy(merged, settings)
+	return context.WithValue(ctx, sessionSettingsKey{}, merged)
+}
+
+// SessionSettingsFromContext returns the settings carried by ctx, if any
+func SessionSettingsFromContext(ctx context.Context) SessionSettings {
+	settings, _ := ctx.Value(sessionSettingsKey{}).(SessionSettings)
+	return settings
+}
+
+// StatementTimeout formats d as a statement_timeout setting
+func StatementTimeout(d time.Duration) string {
+	return fmt.Sprintf("%d", d.Milliseconds())
+}
+
+// InSession runs fn in a transaction on pool (e.g. a replica from
+// GetReadPool) with the settings carried by ctx applied, committing if fn
+// returns nil. Unlike WithTx it does not retry.
+func InSession(ctx context.Context, pool *pgxpool.Pool, fn func(tx pgx.Tx) error) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx) // No-op once committed
+
+	if err := ApplySession(ctx, tx); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// ApplySession applies the settings carried by ctx to tx. They last until the
+// transaction ends, so pooled connections never leak one request's settings
+// into the next. WithTx calls it automatically.
+func ApplySession(ctx context.Context, tx pgx.Tx) error {
+	for name, value := range SessionSettingsFromContext(ctx) {
+		// set_config(..., true) is SET LOCAL with bind parameters
+		if _, err := tx.Exec(ctx, "SELECT set_config($1, $2, true)", name, value); err != nil {
+			return fmt.Errorf("error applying session setting %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package connection
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// queryExecModes maps DatabaseConfig.StatementCacheMode to pgx exec modes
+var queryExecModes = map[string]pgx.QueryExecMode{
+	"cache_statement": pgx.QueryExecModeCacheStatement,
+	"cache_describe":  pgx.QueryExecModeCacheDescribe,
+	"describe_exec":   pgx.QueryExecModeDescribeExec,
+	"exec":            pgx.QueryExecModeExec,
+	"simple_protocol": pgx.QueryExecModeSimpleProtocol,
+}
+
+// StatementCacheStats counts statement cache hits and misses. A query is a
+// miss when pgx had to prepare its statement and a hit otherwise. Counting
+// only happens when the pool runs in a cache mode (cache_statement, the
+// default, or cache_describe); queries overriding the exec mode per call are
+// still counted under the pool's mode.
+type StatementCacheStats struct {
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// Hits returns the number of queries served from the statement cache
+func (s *StatementCacheStats) Hits() int64 {
+	return s.hits.Load()
+}
+
+// Misses returns the number of queries that had to prepare a statement
+func (s *StatementCacheStats) Misses() int64 {
+	return s.misses.Load()
+}
+
+// WithStatementCacheStats records statement cache hits and misses into stats
+func WithStatementCacheStats(stats *StatementCacheStats) Option {
+	return func(o *options) {
+		o.stmtStats = stats
+	}
+}
+
+// cachesStatements reports whether mode uses one of the pgx statement caches
+func cachesStatements(mode string) bool {
+	return mode == "" || mode == "cache_statement" || mode == "cache_describe"
+}
+
+type stmtCacheTracer struct {
+	stats *StatementCacheStats
+}
+
+type stmtCacheKey struct{}
+
+// queryPrepare records whether a traced query prepared its statement
+type queryPrepare struct {
+	prepared bool
+}
+
+func (t *stmtCacheTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, stmtCacheKey{}, &queryPrepare{})
+}
+
+func (t *stmtCacheTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryEndData) {
+	q, ok := ctx.Value(stmtCacheKey{}).(*queryPrepare)
+	if !ok {
+		return
+	}
+	if q.prepared {
+		t.stats.misses.Add(1)
+	} else {
+		t.stats.hits.Add(1)
+	}
+}
+
+func (t *stmtCacheTracer) TracePrepareStart(ctx context.Context, _ *pgx.Conn, _ pgx.TracePrepareStartData) context.Context {
+	return ctx
+}
+
+// TracePrepareEnd only marks prepares made on behalf of a traced query, so
+// explicit Prepare calls and batches don't skew the counts
+func (t *stmtCacheTracer) TracePrepareEnd(ctx context.Context, _ *pgx.Conn, data pgx.TracePrepareEndData) {
+	if q, ok := ctx.Value(stmtCacheKey{}).(*queryPrepare); ok && data.Err == nil && !data.AlreadyPrepared {
+		q.prepared = true
+	}
+}
@@ -0,0 +1,139 @@
+package connection
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ranson21/ranor-common/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Option customizes the pools created by NewDB
+type Option func(*options)
+
+type options struct {
+	tracers   []pgx.QueryTracer
+	stmtStats *StatementCacheStats
+}
+
+// combineTracers merges tracers into one, since pgx accepts only one
+func combineTracers(tracers []pgx.QueryTracer) pgx.QueryTracer {
+	switch len(tracers) {
+	case 0:
+		return nil
+	case 1:
+		return tracers[0]
+	default:
+		return multiTracer(tracers)
+	}
+}
+
+type multiTracer []pgx.QueryTracer
+
+func (m multiTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	for _, t := range m {
+		ctx = t.TraceQueryStart(ctx, conn, data)
+	}
+	return ctx
+}
+
+func (m multiTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	for _, t := range m {
+		t.TraceQueryEnd(ctx, conn, data)
+	}
+}
+
+func (m multiTracer) TracePrepareStart(ctx context.Context, conn *pgx.Conn, data pgx.TracePrepareStartData) context.Context {
+	for _, t := range m {
+		if pt, ok := t.(pgx.PrepareTracer); ok {
+			ctx = pt.TracePrepareStart(ctx, conn, data)
+		}
+	}
+	return ctx
+}
+
+func (m multiTracer) TracePrepareEnd(ctx context.Context, conn *pgx.Conn, data pgx.TracePrepareEndData) {
+	for _, t := range m {
+		if pt, ok := t.(pgx.PrepareTracer); ok {
+			pt.TracePrepareEnd(ctx, conn, data)
+		}
+	}
+}
+
+func (m multiTracer) TraceConnectStart(ctx context.Context, data pgx.TraceConnectStartData) context.Context {
+	for _, t := range m {
+		if ct, ok := t.(pgx.ConnectTracer); ok {
+			ctx = ct.TraceConnectStart(ctx, data)
+		}
+	}
+	return ctx
+}
+
+func (m multiTracer) TraceConnectEnd(ctx context.Context, data pgx.TraceConnectEndData) {
+	for _, t := range m {
+		if ct, ok := t.(pgx.ConnectTracer); ok {
+			ct.TraceConnectEnd(ctx, data)
+		}
+	}
+}
+
+// QueryObserver receives every completed query, e.g. to record duration metrics
+type QueryObserver func(sql string, duration time.Duration, err error)
+
+// WithQueryTracer logs queries through log: failed queries at error level,
+// queries slower than slowThreshold at warn and the rest at debug. observer
+// may be nil.
+func WithQueryTracer(log logger.Logger, slowThreshold time.Duration, observer QueryObserver) Option {
+	return func(o *options) {
+		o.tracers = append(o.tracers, &queryTracer{
+			log:           log,
+			slowThreshold: slowThreshold,
+			observer:      observer,
+		})
+	}
+}
+
+type queryTracer struct {
+	log           logger.Logger
+	slowThreshold time.Duration
+	observer      QueryObserver
+}
+
+type queryStartKey struct{}
+
+type queryStart struct {
+	sql  string
+	time time.Time
+}
+
+func (t *queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryStartKey{}, queryStart{sql: data.SQL, time: time.Now()})
+}
+
+func (t *queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+	duration := time.Since(start.time)
+
+	if t.observer != nil {
+		t.observer(start.sql, duration, data.Err)
+	}
+
+	fields := []zap.Field{
+		zap.String("sql", start.sql),
+		zap.Duration("duration", duration),
+		zap.Int64("rows_affected", data.CommandTag.RowsAffected()),
+	}
+
+	switch {
+	case data.Err != nil:
+		t.log.Error("query failed", append(fields, zap.Error(data.Err))...)
+	case t.slowThreshold > 0 && duration >= t.slowThreshold:
+		t.log.Warn("slow query", fields...)
+	default:
+		t.log.Debug("query", fields...)
+	}
+}
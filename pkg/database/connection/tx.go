@@ -0,0 +1,99 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Postgres error codes that are safe to retry by re-running the transaction
+const (
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+)
+
+// TxOptions controls WithTxOptions
+type TxOptions struct {
+	pgx.TxOptions
+	MaxAttempts int           // Total attempts including the first
+	Backoff     time.Duration // Initial delay between attempts, doubled each time
+}
+
+// DefaultTxOptions returns options retrying up to 5 times
+func DefaultTxOptions() TxOptions {
+	return TxOptions{
+		MaxAttempts: 5,
+		Backoff:     10 * time.Millisecond,
+	}
+}
+
+// WithTx runs fn in a transaction on the primary pool, committing if it
+// returns nil and rolling back otherwise. Serialization failures and
+// deadlocks re-run fn, so fn must be safe to call more than once.
+func WithTx(ctx context.Context, db Database, fn func(tx pgx.Tx) error) error {
+	return WithTxOptions(ctx, db, DefaultTxOptions(), fn)
+}
+
+// WithTxOptions is WithTx with explicit isolation level and retry settings
+func WithTxOptions(ctx context.Context, db Database, opts TxOptions, fn func(tx pgx.Tx) error) error {
+	attempts := opts.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := opts.Backoff
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		err = runTx(ctx, db, opts.TxOptions, fn)
+		if err == nil || !retryable(err) || attempt == attempts {
+			break
+		}
+
+		// Full jitter keeps competing transactions from retrying in lockstep
+		wait := time.Duration(rand.Int63n(int64(delay) + 1))
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(wait):
+		}
+		delay *= 2
+	}
+	return err
+}
+
+func runTx(ctx context.Context, db Database, opts pgx.TxOptions, fn func(tx pgx.Tx) error) error {
+	tx, err := db.GetWritePool().BeginTx(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+
+	if err := ApplySession(ctx, tx); err != nil {
+		_ = tx.Rollback(ctx)
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(ctx); rbErr != nil && !errors.Is(rbErr, pgx.ErrTxClosed) {
+			return errors.Join(err, fmt.Errorf("error rolling back transaction: %w", rbErr))
+		}
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+func retryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == serializationFailure || pgErr.Code == deadlockDetected
+}
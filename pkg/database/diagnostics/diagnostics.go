@@ -0,0 +1,238 @@
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ranson21/ranor-common/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// QueryStat represents a single entry from pg_stat_statements
+type QueryStat struct {
+	QueryID       int64         `json:"query_id"`
+	Query         string        `json:"query"`
+	Calls         int64         `json:"calls"`
+	Rows          int64         `json:"rows"`
+	TotalExecTime time.Duration `json:"total_exec_time"`
+	MeanExecTime  time.Duration `json:"mean_exec_time"`
+}
+
+// Snapshot holds the top queries captured at a point in time
+type Snapshot struct {
+	Service   string      `json:"service"`
+	Queries   []QueryStat `json:"queries"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// Config holds slow query collector configuration
+type Config struct {
+	Service  string        // Service name attached to every snapshot
+	Interval time.Duration // How often snapshots are taken
+	Limit    int           // Number of top queries kept per snapshot
+	Table    string        // Optional table snapshots are persisted to, may be schema-qualified
+}
+
+// DefaultConfig returns a default configuration
+func DefaultConfig(service string) *Config {
+	return &Config{
+		Service:  service,
+		Interval: 5 * time.Minute,
+		Limit:    20,
+	}
+}
+
+// Collector periodically samples pg_stat_statements for the current database
+type Collector struct {
+	pool   *pgxpool.Pool
+	log    logger.Logger
+	config *Config
+
+	mu     sync.RWMutex
+	latest *Snapshot
+}
+
+func NewCollector(pool *pgxpool.Pool, log logger.Logger, config *Config) *Collector {
+	if config == nil {
+		config = DefaultConfig("")
+	}
+	return &Collector{
+		pool:   pool,
+		log:    log,
+		config: config,
+	}
+}
+
+// EnableExtension creates the pg_stat_statements extension if it is missing.
+// The extension must also be listed in shared_preload_libraries on the server.
+func (c *Collector) EnableExtension(ctx context.Context) error {
+	if _, err := c.pool.Exec(ctx, "CREATE EXTENSION IF NOT EXISTS pg_stat_statements"); err != nil {
+		return fmt.Errorf("error enabling pg_stat_statements: %w", err)
+	}
+
+	if c.config.Table == "" {
+		return nil
+	}
+
+	_, err := c.pool.Exec(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id BIGSERIAL PRIMARY KEY,
+			service TEXT NOT NULL,
+			query_id BIGINT NOT NULL,
+			query TEXT NOT NULL,
+			calls BIGINT NOT NULL,
+			rows BIGINT NOT NULL,
+			total_exec_ms DOUBLE PRECISION NOT NULL,
+			mean_exec_ms DOUBLE PRECISION NOT NULL,
+			captured_at TIMESTAMPTZ NOT NULL
+		)`, c.table()))
+	if err != nil {
+		return fmt.Errorf("error creating diagnostics table: %w", err)
+	}
+
+	return nil
+}
+
+// Capture reads the top queries by total execution time for the current database
+func (c *Collector) Capture(ctx context.Context) (*Snapshot, error) {
+	rows, err := c.pool.Query(ctx, `
+		SELECT s.queryid, s.query, s.calls, s.rows, s.total_exec_time, s.mean_exec_time
+		FROM pg_stat_statements s
+		JOIN pg_database d ON d.oid = s.dbid
+		WHERE d.datname = current_database()
+		ORDER BY s.total_exec_time DESC
+		LIMIT $1`, c.config.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying pg_stat_statements: %w", err)
+	}
+	defer rows.Close()
+
+	snapshot := &Snapshot{
+		Service:   c.config.Service,
+		Timestamp: time.Now(),
+	}
+
+	for rows.Next() {
+		var (
+			stat        QueryStat
+			total, mean float64
+		)
+		if err := rows.Scan(&stat.QueryID, &stat.Query, &stat.Calls, &stat.Rows, &total, &mean); err != nil {
+			return nil, fmt.Errorf("error scanning pg_stat_statements row: %w", err)
+		}
+		stat.TotalExecTime = millis(total)
+		stat.MeanExecTime = millis(mean)
+		snapshot.Queries = append(snapshot.Queries, stat)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading pg_stat_statements: %w", err)
+	}
+
+	c.mu.Lock()
+	c.latest = snapshot
+	c.mu.Unlock()
+
+	return snapshot, nil
+}
+
+// Start captures a snapshot every interval until the context is cancelled
+func (c *Collector) Start(ctx context.Context) {
+	ticker := time.NewTicker(c.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			snapshot, err := c.Capture(ctx)
+			if err != nil {
+				c.log.Error("slow query capture failed", zap.Error(err))
+				continue
+			}
+			c.record(ctx, snapshot)
+		}
+	}
+}
+
+// Latest returns the most recent snapshot, or nil if none has been captured
+func (c *Collector) Latest() *Snapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.latest
+}
+
+// Handler serves the latest snapshot as JSON. The authorize handler runs first
+// and must abort the request for callers lacking admin permissions; a nil
+// authorize rejects every request.
+func (c *Collector) Handler(authorize gin.HandlerFunc) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if authorize == nil {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			ctx.Abort()
+			return
+		}
+
+		authorize(ctx)
+		if ctx.IsAborted() {
+			return
+		}
+
+		snapshot := c.Latest()
+		if snapshot == nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "No snapshot captured yet"})
+			return
+		}
+		ctx.JSON(http.StatusOK, snapshot)
+	}
+}
+
+func (c *Collector) record(ctx context.Context, snapshot *Snapshot) {
+	for _, q := range snapshot.Queries {
+		c.log.Info("slow query snapshot",
+			zap.String("service", snapshot.Service),
+			zap.Int64("query_id", q.QueryID),
+			zap.Int64("calls", q.Calls),
+			zap.Duration("total_exec_time", q.TotalExecTime),
+			zap.Duration("mean_exec_time", q.MeanExecTime),
+			zap.String("query", q.Query),
+		)
+	}
+
+	if c.config.Table == "" {
+		return
+	}
+
+	for _, q := range snapshot.Queries {
+		_, err := c.pool.Exec(ctx, fmt.Sprintf(`
+			INSERT INTO %s (service, query_id, query, calls, rows, total_exec_ms, mean_exec_ms, captured_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`, c.table()),
+			snapshot.Service, q.QueryID, q.Query, q.Calls, q.Rows,
+			toMillis(q.TotalExecTime), toMillis(q.MeanExecTime), snapshot.Timestamp,
+		)
+		if err != nil {
+			c.log.Error("error persisting slow query snapshot", zap.Error(err))
+			return
+		}
+	}
+}
+
+// table returns the quoted snapshot table name
+func (c *Collector) table() string {
+	return pgx.Identifier(strings.Split(c.config.Table, ".")).Sanitize()
+}
+
+func millis(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+func toMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,181 @@
+package invalidation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ranson21/ranor-common/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// DefaultChannel is the NOTIFY channel used when Config.Channel is empty
+const DefaultChannel = "cache_invalidation"
+
+// DefaultReconnectDelay is used when Config.ReconnectDelay is not positive
+const DefaultReconnectDelay = 5 * time.Second
+
+// maxPayload is the Postgres limit on NOTIFY payloads (in bytes, less one)
+const maxPayload = 7999
+
+// Message identifies the entity whose cached copies are stale. An empty ID
+// means every entity of the type.
+type Message struct {
+	Entity string `json:"entity"`
+	ID     string `json:"id,omitempty"`
+}
+
+// Handler evicts cached entries for a message
+type Handler func(Message)
+
+// Execer is satisfied by pgx.Tx, *pgx.Conn and *pgxpool.Pool
+type Execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+type Config struct {
+	Channel        string
+	ReconnectDelay time.Duration // Wait between attempts to re-establish LISTEN
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Channel:        DefaultChannel,
+		ReconnectDelay: DefaultReconnectDelay,
+	}
+}
+
+// Bus delivers invalidation messages published by any instance to the
+// handlers subscribed in every instance sharing the database
+type Bus struct {
+	pool   *pgxpool.Pool
+	log    logger.Logger
+	config *Config
+
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+	resets   []func()
+}
+
+// New creates a bus. Missing config values take their defaults; the caller's
+// config is not modified.
+func New(pool *pgxpool.Pool, log logger.Logger, config *Config) *Bus {
+	cfg := *DefaultConfig()
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.Channel == "" {
+		cfg.Channel = DefaultChannel
+	}
+	if cfg.ReconnectDelay <= 0 {
+		cfg.ReconnectDelay = DefaultReconnectDelay
+	}
+	return &Bus{
+		pool:     pool,
+		log:      log,
+		config:   &cfg,
+		handlers: make(map[string][]Handler),
+	}
+}
+
+// Publish notifies every subscriber that entity id changed. Publishing through
+// the transaction making the write means the message is only delivered if it
+// commits, and never before.
+func (b *Bus) Publish(ctx context.Context, db Execer, entity, id string) error {
+	payload, err := json.Marshal(Message{Entity: entity, ID: id})
+	if err != nil {
+		return fmt.Errorf("error encoding invalidation: %w", err)
+	}
+	if len(payload) > maxPayload {
+		return fmt.Errorf("invalidation for %s exceeds the NOTIFY payload limit", entity)
+	}
+
+	if _, err := db.Exec(ctx, "SELECT pg_notify($1, $2)", b.config.Channel, string(payload)); err != nil {
+		return fmt.Errorf("error publishing invalidation: %w", err)
+	}
+	return nil
+}
+
+// Subscribe registers fn for messages about entity
+func (b *Bus) Subscribe(entity string, fn Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[entity] = append(b.handlers[entity], fn)
+}
+
+// OnReset registers fn to run whenever the listener (re)connects. Messages
+// sent while disconnected are lost, so caches should drop everything.
+func (b *Bus) OnReset(fn func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.resets = append(b.resets, fn)
+}
+
+// Run listens for messages until ctx is cancelled, reconnecting on failure
+func (b *Bus) Run(ctx context.Context) {
+	for {
+		err := b.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		b.log.Error("Invalidation listener disconnected", zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(b.config.ReconnectDelay):
+		}
+	}
+}
+
+func (b *Bus) listen(ctx context.Context) error {
+	pooled, err := b.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("error acquiring connection: %w", err)
+	}
+	// The connection carries LISTEN state, so never hand it back to the pool
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{b.config.Channel}.Sanitize()); err != nil {
+		return fmt.Errorf("error listening on %s: %w", b.config.Channel, err)
+	}
+	b.reset()
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var msg Message
+		if err := json.Unmarshal([]byte(n.Payload), &msg); err != nil {
+			b.log.Warn("Ignoring malformed invalidation", zap.String("payload", n.Payload), zap.Error(err))
+			continue
+		}
+		b.dispatch(msg)
+	}
+}
+
+func (b *Bus) reset() {
+	b.mu.RLock()
+	resets := b.resets
+	b.mu.RUnlock()
+	for _, fn := range resets {
+		fn()
+	}
+}
+
+func (b *Bus) dispatch(msg Message) {
+	b.mu.RLock()
+	handlers := b.handlers[msg.Entity]
+	b.mu.RUnlock()
+	for _, fn := range handlers {
+		fn(msg)
+	}
+}
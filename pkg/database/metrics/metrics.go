@@ -0,0 +1,151 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/ranson21/ranor-common/pkg/database/connection"
+)
+
+// PoolCollector exports pgxpool.Stat for one or more named pools
+type PoolCollector struct {
+	mu    sync.RWMutex
+	pools map[string]*pgxpool.Pool
+
+	acquiredConns        *prometheus.Desc
+	idleConns            *prometheus.Desc
+	constructingConns    *prometheus.Desc
+	totalConns           *prometheus.Desc
+	maxConns             *prometheus.Desc
+	acquireCount         *prometheus.Desc
+	emptyAcquireCount    *prometheus.Desc
+	canceledAcquireCount *prometheus.Desc
+	acquireDuration      *prometheus.Desc
+	newConnsCount        *prometheus.Desc
+}
+
+// Ensure PoolCollector implements prometheus.Collector
+var _ prometheus.Collector = (*PoolCollector)(nil)
+
+func NewPoolCollector() *PoolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc("pgxpool_"+name, help, []string{"pool"}, nil)
+	}
+
+	return &PoolCollector{
+		pools: make(map[string]*pgxpool.Pool),
+
+		acquiredConns:        desc("acquired_conns", "Connections currently checked out of the pool."),
+		idleConns:            desc("idle_conns", "Idle connections in the pool."),
+		constructingConns:    desc("constructing_conns", "Connections currently being established."),
+		totalConns:           desc("total_conns", "Total connections in the pool."),
+		maxConns:             desc("max_conns", "Maximum size of the pool."),
+		acquireCount:         desc("acquire_total", "Successful connection acquisitions."),
+		emptyAcquireCount:    desc("empty_acquire_total", "Acquisitions that had to wait because the pool was empty."),
+		canceledAcquireCount: desc("canceled_acquire_total", "Acquisitions cancelled by their context."),
+		acquireDuration:      desc("acquire_duration_seconds_total", "Total time spent waiting to acquire connections."),
+		newConnsCount:        desc("new_conns_total", "Connections opened by the pool."),
+	}
+}
+
+// Add registers a pool under name, replacing any pool with the same name
+func (c *PoolCollector) Add(name string, pool *pgxpool.Pool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pools[name] = pool
+}
+
+// Remove stops exporting the named pool
+func (c *PoolCollector) Remove(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pools, name)
+}
+
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.constructingConns
+	ch <- c.totalConns
+	ch <- c.maxConns
+	ch <- c.acquireCount
+	ch <- c.emptyAcquireCount
+	ch <- c.canceledAcquireCount
+	ch <- c.acquireDuration
+	ch <- c.newConnsCount
+}
+
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for name, pool := range c.pools {
+		s := pool.Stat()
+		gauge := func(d *prometheus.Desc, v float64) {
+			ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, v, name)
+		}
+		counter := func(d *prometheus.Desc, v float64) {
+			ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, v, name)
+		}
+
+		gauge(c.acquiredConns, float64(s.AcquiredConns()))
+		gauge(c.idleConns, float64(s.IdleConns()))
+		gauge(c.constructingConns, float64(s.ConstructingConns()))
+		gauge(c.totalConns, float64(s.TotalConns()))
+		gauge(c.maxConns, float64(s.MaxConns()))
+		counter(c.acquireCount, float64(s.AcquireCount()))
+		counter(c.emptyAcquireCount, float64(s.EmptyAcquireCount()))
+		counter(c.canceledAcquireCount, float64(s.CanceledAcquireCount()))
+		counter(c.acquireDuration, s.AcquireDuration().Seconds())
+		counter(c.newConnsCount, float64(s.NewConnsCount()))
+	}
+}
+
+// Register creates a collector for the named pools and registers it with reg
+// (prometheus.DefaultRegisterer when nil)
+func Register(reg prometheus.Registerer, pools map[string]*pgxpool.Pool) (*PoolCollector, error) {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+
+	c := NewPoolCollector()
+	for name, pool := range pools {
+		c.Add(name, pool)
+	}
+	if err := reg.Register(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// StatementCacheCollector exports connection.StatementCacheStats per named pool
+type StatementCacheCollector struct {
+	name   string
+	stats  *connection.StatementCacheStats
+	hits   *prometheus.Desc
+	misses *prometheus.Desc
+}
+
+// Ensure StatementCacheCollector implements prometheus.Collector
+var _ prometheus.Collector = (*StatementCacheCollector)(nil)
+
+func NewStatementCacheCollector(name string, stats *connection.StatementCacheStats) *StatementCacheCollector {
+	labels := prometheus.Labels{"pool": name}
+	return &StatementCacheCollector{
+		name:   name,
+		stats:  stats,
+		hits:   prometheus.NewDesc("pgx_statement_cache_hits_total", "Queries served from the prepared statement cache.", nil, labels),
+		misses: prometheus.NewDesc("pgx_statement_cache_misses_total", "Queries that had to prepare a statement.", nil, labels),
+	}
+}
+
+func (c *StatementCacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+}
+
+func (c *StatementCacheCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(c.stats.Hits()))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(c.stats.Misses()))
+}
@@ -0,0 +1,61 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrNotFound is returned by One when the query matches no rows
+var ErrNotFound = errors.New("not found")
+
+// Querier is satisfied by *pgxpool.Pool, *pgx.Conn and pgx.Tx
+type Querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// One scans the single row returned by sql into a T. Columns map to fields by
+// their `db` tag, or the field name when untagged; `db:"-"` skips a field.
+// Every column must have a matching field.
+func One[T any](ctx context.Context, q Querier, sql string, args ...any) (*T, error) {
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying: %w", err)
+	}
+
+	row, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[T])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error scanning row: %w", err)
+	}
+	return row, nil
+}
+
+// All scans every row returned by sql into a T, mapped as in One
+func All[T any](ctx context.Context, q Querier, sql string, args ...any) ([]T, error) {
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying: %w", err)
+	}
+
+	items, err := pgx.CollectRows(rows, pgx.RowToStructByName[T])
+	if err != nil {
+		return nil, fmt.Errorf("error scanning rows: %w", err)
+	}
+	return items, nil
+}
+
+// Exec runs sql and returns the number of rows affected
+func Exec(ctx context.Context, q Querier, sql string, args ...any) (int64, error) {
+	tag, err := q.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, fmt.Errorf("error executing: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
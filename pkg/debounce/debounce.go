@@ -0,0 +1,148 @@
+package debounce
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock abstracts timers so callers can drive debouncing deterministically
+type Clock interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is the subset of *time.Timer used here
+type Timer interface {
+	Stop() bool
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer { return time.AfterFunc(d, f) }
+
+// RealClock is the wall clock
+var RealClock Clock = realClock{}
+
+// Debouncer coalesces repeated triggers per key and calls fn once the key has
+// been quiet for the wait period
+type Debouncer struct {
+	wait  time.Duration
+	clock Clock
+	fn    func(key string)
+
+	mu     sync.Mutex
+	timers map[string]Timer
+}
+
+func NewDebouncer(wait time.Duration, clock Clock, fn func(key string)) *Debouncer {
+	if clock == nil {
+		clock = RealClock
+	}
+	return &Debouncer{
+		wait:   wait,
+		clock:  clock,
+		fn:     fn,
+		timers: make(map[string]Timer),
+	}
+}
+
+// Trigger schedules fn for key, pushing back any pending call
+func (d *Debouncer) Trigger(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if t, exists := d.timers[key]; exists {
+		t.Stop()
+	}
+
+	var t Timer
+	t = d.clock.AfterFunc(d.wait, func() {
+		d.mu.Lock()
+		// A later Trigger may have replaced this timer after it fired
+		if d.timers[key] != t {
+			d.mu.Unlock()
+			return
+		}
+		delete(d.timers, key)
+		d.mu.Unlock()
+
+		d.fn(key)
+	})
+	d.timers[key] = t
+}
+
+// Cancel drops the pending call for key, if any
+func (d *Debouncer) Cancel(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if t, exists := d.timers[key]; exists {
+		t.Stop()
+		delete(d.timers, key)
+	}
+}
+
+// Pending returns the number of keys waiting to fire
+func (d *Debouncer) Pending() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.timers)
+}
+
+// Throttler allows at most one call per key per interval
+type Throttler struct {
+	interval time.Duration
+	clock    Clock
+
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+func NewThrottler(interval time.Duration, clock Clock) *Throttler {
+	if clock == nil {
+		clock = RealClock
+	}
+	return &Throttler{
+		interval: interval,
+		clock:    clock,
+		last:     make(map[string]time.Time),
+	}
+}
+
+// Allow reports whether a call for key may proceed now, recording it if so
+func (t *Throttler) Allow(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.clock.Now()
+	if last, exists := t.last[key]; exists && now.Sub(last) < t.interval {
+		return false
+	}
+	t.last[key] = now
+	return true
+}
+
+// Do calls fn if key isn't throttled and reports whether it ran
+func (t *Throttler) Do(key string, fn func()) bool {
+	if !t.Allow(key) {
+		return false
+	}
+	fn()
+	return true
+}
+
+// Cleanup forgets keys whose interval has passed, bounding memory for
+// high-cardinality keys
+func (t *Throttler) Cleanup() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.clock.Now()
+	for key, last := range t.last {
+		if now.Sub(last) >= t.interval {
+			delete(t.last, key)
+		}
+	}
+}
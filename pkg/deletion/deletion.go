@@ -0,0 +1,338 @@
+package deletion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Status is the state of an account deletion request
+type Status string
+
+const (
+	SoftDeleted Status = "soft_deleted" // Hidden and pending purge, can still be cancelled
+	Cancelled   Status = "cancelled"
+	Purging     Status = "purging" // Claimed by a PurgeDue run
+	Purged      Status = "purged"
+	Failed      Status = "failed"
+)
+
+// claimTimeout is how long a purge claim is held before another run may
+// take it over, e.g. after the claiming instance crashed
+const claimTimeout = time.Hour
+
+// purgeBatchSize bounds the accounts claimed by one PurgeDue run
+const purgeBatchSize = 100
+
+// Event types published as the deletion progresses
+const (
+	EventRequested = "account.deletion_requested"
+	EventCancelled = "account.deletion_cancelled"
+	EventPurged    = "account.purged"
+)
+
+var (
+	ErrNotFound      = errors.New("deletion: no request for user")
+	ErrNotCancelable = errors.New("deletion: request can no longer be cancelled")
+)
+
+// Request tracks a single account's deletion
+type Request struct {
+	UserID      string     `json:"user_id"`
+	Status      Status     `json:"status"`
+	RequestedAt time.Time  `json:"requested_at"`
+	PurgeAfter  time.Time  `json:"purge_after"`
+	PurgedAt    *time.Time `json:"purged_at,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// Event is fanned out to other services so they can delete their own copies
+type Event struct {
+	Type       string    `json:"type"`
+	UserID     string    `json:"user_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Publisher delivers deletion events to other services
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// Participant is a local data owner taking part in the deletion. SoftDelete
+// and Restore run during the grace period; Purge removes the data for good.
+// Purge may run again for the same user if an earlier run was interrupted,
+// so it must be idempotent.
+type Participant interface {
+	Name() string
+	SoftDelete(ctx context.Context, userID string) error
+	Restore(ctx context.Context, userID string) error
+	Purge(ctx context.Context, userID string) error
+}
+
+// Orchestrator runs the soft-delete, grace period and purge steps
+type Orchestrator struct {
+	pool         *pgxpool.Pool
+	publisher    Publisher
+	participants []Participant
+	grace        time.Duration
+}
+
+func NewOrchestrator(pool *pgxpool.Pool, publisher Publisher, grace time.Duration, participants ...Participant) *Orchestrator {
+	return &Orchestrator{
+		pool:         pool,
+		publisher:    publisher,
+		participants: participants,
+		grace:        grace,
+	}
+}
+
+// EnsureSchema creates the deletion request table if it doesn't exist
+func (o *Orchestrator) EnsureSchema(ctx context.Context) error {
+	_, err := o.pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS account_deletions (
+			user_id TEXT PRIMARY KEY,
+			status TEXT NOT NULL,
+			requested_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			purge_after TIMESTAMPTZ NOT NULL,
+			purged_at TIMESTAMPTZ,
+			error TEXT NOT NULL DEFAULT ''
+		);
+		ALTER TABLE account_deletions
+			ADD COLUMN IF NOT EXISTS claimed_at TIMESTAMPTZ,
+			ADD COLUMN IF NOT EXISTS purge_published BOOLEAN NOT NULL DEFAULT false`)
+	if err != nil {
+		return fmt.Errorf("error creating deletion schema: %w", err)
+	}
+	return nil
+}
+
+// Request soft-deletes the account and schedules the purge after the grace
+// period. If a participant fails, the ones already applied are restored.
+func (o *Orchestrator) Request(ctx context.Context, userID string) (*Request, error) {
+	for i, p := range o.participants {
+		if err := p.SoftDelete(ctx, userID); err != nil {
+			o.restore(ctx, userID, o.participants[:i])
+			return nil, fmt.Errorf("error soft deleting %s: %w", p.Name(), err)
+		}
+	}
+
+	req := &Request{UserID: userID, Status: SoftDeleted}
+	err := o.pool.QueryRow(ctx, `
+		INSERT INTO account_deletions (user_id, status, purge_after)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+			SET status = EXCLUDED.status, requested_at = now(), purge_after = EXCLUDED.purge_after, error = ''
+		RETURNING requested_at, purge_after`,
+		userID, SoftDeleted, time.Now().Add(o.grace),
+	).Scan(&req.RequestedAt, &req.PurgeAfter)
+	if err != nil {
+		o.restore(ctx, userID, o.participants)
+		return nil, fmt.Errorf("error recording deletion request: %w", err)
+	}
+
+	if err := o.publish(ctx, EventRequested, userID); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// Cancel restores an account that is still within its grace period. The
+// request is claimed as cancelled first, so a concurrent PurgeDue can no
+// longer pick it up. If a participant fails to restore, the request goes back
+// to soft deleted with the error recorded and Cancel can be retried.
+func (o *Orchestrator) Cancel(ctx context.Context, userID string) error {
+	tag, err := o.pool.Exec(ctx,
+		"UPDATE account_deletions SET status = $2, error = '' WHERE user_id = $1 AND status = $3",
+		userID, Cancelled, SoftDeleted)
+	if err != nil {
+		return fmt.Errorf("error cancelling deletion: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		if _, err := o.Status(ctx, userID); err != nil {
+			return err
+		}
+		return ErrNotCancelable
+	}
+
+	for _, p := range o.participants {
+		if err := p.Restore(ctx, userID); err != nil {
+			err = fmt.Errorf("error restoring %s: %w", p.Name(), err)
+			if serr := o.setStatus(ctx, userID, SoftDeleted, err.Error()); serr != nil {
+				return errors.Join(err, serr)
+			}
+			return err
+		}
+	}
+
+	return o.publish(ctx, EventCancelled, userID)
+}
+
+// PurgeDue hard-deletes every account whose grace period has elapsed and
+// returns how many were purged. Accounts are claimed first so concurrent runs
+// on several instances never purge the same user. Purge failures are recorded
+// on the request and the remaining accounts are still processed. Purged
+// events that could not be published are retried on the next run.
+func (o *Orchestrator) PurgeDue(ctx context.Context) (int, error) {
+	if err := o.republish(ctx); err != nil {
+		return 0, err
+	}
+
+	rows, err := o.pool.Query(ctx, `
+		UPDATE account_deletions SET status = $1, claimed_at = now()
+		WHERE user_id IN (
+			SELECT user_id FROM account_deletions
+			WHERE (status = $2 AND purge_after <= now())
+			OR (status = $1 AND claimed_at < $3)
+			ORDER BY purge_after
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING user_id`,
+		Purging, SoftDeleted, time.Now().Add(-claimTimeout), purgeBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("error claiming due deletions: %w", err)
+	}
+	userIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return 0, fmt.Errorf("error claiming due deletions: %w", err)
+	}
+
+	purged := 0
+	for _, userID := range userIDs {
+		if err := o.purge(ctx, userID); err != nil {
+			if err := o.setStatus(ctx, userID, Failed, err.Error()); err != nil {
+				return purged, err
+			}
+			continue
+		}
+		purged++
+
+		// The data is gone either way, so a publish failure leaves the request
+		// purged and is retried by the next run
+		if err := o.publishPurged(ctx, userID); err != nil {
+			if err := o.setStatus(ctx, userID, Purged, err.Error()); err != nil {
+				return purged, err
+			}
+		}
+	}
+	return purged, nil
+}
+
+// Status returns the deletion request for a user
+func (o *Orchestrator) Status(ctx context.Context, userID string) (*Request, error) {
+	req := &Request{UserID: userID}
+	err := o.pool.QueryRow(ctx, `
+		SELECT status, requested_at, purge_after, purged_at, error
+		FROM account_deletions WHERE user_id = $1`, userID,
+	).Scan(&req.Status, &req.RequestedAt, &req.PurgeAfter, &req.PurgedAt, &req.Error)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error loading deletion request: %w", err)
+	}
+	return req, nil
+}
+
+func (o *Orchestrator) purge(ctx context.Context, userID string) error {
+	for _, p := range o.participants {
+		if err := p.Purge(ctx, userID); err != nil {
+			return fmt.Errorf("error purging %s: %w", p.Name(), err)
+		}
+	}
+
+	_, err := o.pool.Exec(ctx,
+		"UPDATE account_deletions SET status = $2, purged_at = now(), purge_published = false, error = '' WHERE user_id = $1",
+		userID, Purged)
+	if err != nil {
+		return fmt.Errorf("error recording purge: %w", err)
+	}
+	return nil
+}
+
+// publishPurged announces the purge and records that it was delivered
+func (o *Orchestrator) publishPurged(ctx context.Context, userID string) error {
+	if err := o.publish(ctx, EventPurged, userID); err != nil {
+		return err
+	}
+	_, err := o.pool.Exec(ctx,
+		"UPDATE account_deletions SET purge_published = true, error = '' WHERE user_id = $1", userID)
+	if err != nil {
+		return fmt.Errorf("error recording purge publication: %w", err)
+	}
+	return nil
+}
+
+// republish retries purged events whose publication failed. The rows stay
+// locked while publishing so concurrent runs skip them.
+func (o *Orchestrator) republish(ctx context.Context) error {
+	tx, err := o.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT user_id FROM account_deletions
+		WHERE status = $1 AND NOT purge_published
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED`, Purged, purgeBatchSize)
+	if err != nil {
+		return fmt.Errorf("error loading unpublished purges: %w", err)
+	}
+	userIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("error loading unpublished purges: %w", err)
+	}
+
+	for _, userID := range userIDs {
+		msg := ""
+		published := true
+		if err := o.publish(ctx, EventPurged, userID); err != nil {
+			msg, published = err.Error(), false
+		}
+		_, err := tx.Exec(ctx,
+			"UPDATE account_deletions SET purge_published = $2, error = $3 WHERE user_id = $1",
+			userID, published, msg)
+		if err != nil {
+			return fmt.Errorf("error recording purge publication: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing purge publications: %w", err)
+	}
+	return nil
+}
+
+// restore undoes SoftDelete for participants, best effort
+func (o *Orchestrator) restore(ctx context.Context, userID string, participants []Participant) {
+	for _, p := range participants {
+		_ = p.Restore(ctx, userID)
+	}
+}
+
+func (o *Orchestrator) setStatus(ctx context.Context, userID string, status Status, msg string) error {
+	_, err := o.pool.Exec(ctx,
+		"UPDATE account_deletions SET status = $2, error = $3 WHERE user_id = $1",
+		userID, status, msg)
+	if err != nil {
+		return fmt.Errorf("error updating deletion status: %w", err)
+	}
+	return nil
+}
+
+func (o *Orchestrator) publish(ctx context.Context, eventType, userID string) error {
+	if o.publisher == nil {
+		return nil
+	}
+	err := o.publisher.Publish(ctx, Event{Type: eventType, UserID: userID, OccurredAt: time.Now()})
+	if err != nil {
+		return fmt.Errorf("error publishing %s: %w", eventType, err)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package dnscache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Config holds resolver cache configuration. The standard library resolver
+// doesn't expose record TTLs, so TTL acts as the maximum time an answer is
+// reused.
+type Config struct {
+	TTL         time.Duration // How long successful lookups are cached
+	NegativeTTL time.Duration // How long failed lookups are cached
+	Resolver    *net.Resolver
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		TTL:         30 * time.Second,
+		NegativeTTL: 5 * time.Second,
+		Resolver:    net.DefaultResolver,
+	}
+}
+
+type entry struct {
+	addrs   []string
+	err     error
+	expires time.Time
+}
+
+// Resolver caches host lookups and collapses concurrent lookups for the same
+// host into one query
+type Resolver struct {
+	config *Config
+	group  singleflight.Group
+
+	mu    sync.RWMutex
+	cache map[string]entry
+}
+
+func NewResolver(config *Config) *Resolver {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if config.Resolver == nil {
+		config.Resolver = net.DefaultResolver
+	}
+	return &Resolver{
+		config: config,
+		cache:  make(map[string]entry),
+	}
+}
+
+// LookupHost returns the cached addresses for host, resolving on a miss
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	r.mu.RLock()
+	e, exists := r.cache[host]
+	r.mu.RUnlock()
+
+	if exists && time.Now().Before(e.expires) {
+		return e.addrs, e.err
+	}
+
+	v, err, _ := r.group.Do(host, func() (any, error) {
+		// Detach from the caller's cancellation so one cancelled request
+		// doesn't fail every caller waiting on the same lookup
+		addrs, err := r.config.Resolver.LookupHost(context.WithoutCancel(ctx), host)
+
+		ttl := r.config.TTL
+		if err != nil {
+			ttl = r.config.NegativeTTL
+		}
+		r.mu.Lock()
+		r.cache[host] = entry{addrs: addrs, err: err, expires: time.Now().Add(ttl)}
+		r.mu.Unlock()
+
+		return addrs, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]string), nil
+}
+
+// Refresh drops cached entries that have expired. Call it periodically in
+// long-running services to keep the cache from growing unbounded.
+func (r *Resolver) Refresh() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for host, e := range r.cache {
+		if now.After(e.expires) {
+			delete(r.cache, host)
+		}
+	}
+}
+
+// DialContext returns a dial function for http.Transport.DialContext that
+// resolves through the cache and tries each address in turn
+func (r *Resolver) DialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if dialer == nil {
+		dialer = &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if net.ParseIP(host) != nil {
+			return dialer.DialContext(ctx, network, addr)
+		}
+
+		addrs, err := r.LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		var errs []error
+		for _, ip := range addrs {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			errs = append(errs, err)
+		}
+		return nil, errors.Join(errs...)
+	}
+}
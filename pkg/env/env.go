@@ -0,0 +1,109 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parser reads typed environment variables and collects every error so a
+// service can report all bad settings at startup in one go
+type Parser struct {
+	lookup func(string) (string, bool)
+	errs   []error
+}
+
+// New creates a parser over the process environment
+func New() *Parser {
+	return &Parser{lookup: os.LookupEnv}
+}
+
+// NewWithLookup creates a parser over a custom source, e.g. a map in tests
+func NewWithLookup(lookup func(string) (string, bool)) *Parser {
+	return &Parser{lookup: lookup}
+}
+
+// Err returns every error recorded so far, or nil
+func (p *Parser) Err() error {
+	return errors.Join(p.errs...)
+}
+
+// Get returns the parsed value of key, or def if it is unset or empty. An
+// unparsable value is recorded as an error and def is returned.
+func Get[T any](p *Parser, key string, def T) T {
+	raw, ok := p.lookup(key)
+	if !ok || raw == "" {
+		return def
+	}
+
+	v, err := parse[T](raw)
+	if err != nil {
+		p.errs = append(p.errs, fmt.Errorf("invalid value for %s: %w", key, err))
+		return def
+	}
+	return v
+}
+
+// Required returns the parsed value of key, recording an error if it is
+// unset, empty or unparsable
+func Required[T any](p *Parser, key string) T {
+	var zero T
+
+	raw, ok := p.lookup(key)
+	if !ok || raw == "" {
+		p.errs = append(p.errs, fmt.Errorf("%s is required", key))
+		return zero
+	}
+
+	v, err := parse[T](raw)
+	if err != nil {
+		p.errs = append(p.errs, fmt.Errorf("invalid value for %s: %w", key, err))
+		return zero
+	}
+	return v
+}
+
+func parse[T any](raw string) (T, error) {
+	var (
+		zero T
+		v    any
+		err  error
+	)
+
+	switch any(zero).(type) {
+	case string:
+		v = raw
+	case int:
+		v, err = strconv.Atoi(raw)
+	case int32:
+		var n int64
+		n, err = strconv.ParseInt(raw, 10, 32)
+		v = int32(n)
+	case int64:
+		v, err = strconv.ParseInt(raw, 10, 64)
+	case float64:
+		v, err = strconv.ParseFloat(raw, 64)
+	case bool:
+		v, err = strconv.ParseBool(raw)
+	case time.Duration:
+		v, err = time.ParseDuration(raw)
+	case []string:
+		parts := strings.Split(raw, ",")
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		v = parts
+	case *url.URL:
+		v, err = url.Parse(raw)
+	default:
+		return zero, fmt.Errorf("unsupported type %T", zero)
+	}
+	if err != nil {
+		return zero, err
+	}
+	return v.(T), nil
+}
@@ -0,0 +1,72 @@
+package env
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func lookup(vars map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := vars[key]
+		return v, ok
+	}
+}
+
+func TestGet(t *testing.T) {
+	p := NewWithLookup(lookup(map[string]string{
+		"NAME":     "api",
+		"EMPTY":    "",
+		"PORT":     "8080",
+		"CONNS":    "20",
+		"RATE":     "0.25",
+		"DEBUG":    "true",
+		"TIMEOUT":  "1m30s",
+		"HOSTS":    "a, b ,c",
+		"BAD_INT":  "ten",
+		"BAD_BOOL": "maybe",
+		"BIG":      "3000000000",
+	}))
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"string", Get(p, "NAME", "default"), "api"},
+		{"unset", Get(p, "MISSING", "default"), "default"},
+		{"empty", Get(p, "EMPTY", "default"), "default"},
+		{"int", Get(p, "PORT", 0), 8080},
+		{"int32", Get(p, "CONNS", int32(5)), int32(20)},
+		{"float64", Get(p, "RATE", 1.0), 0.25},
+		{"bool", Get(p, "DEBUG", false), true},
+		{"duration", Get(p, "TIMEOUT", time.Second), 90 * time.Second},
+		{"string slice", Get(p, "HOSTS", []string(nil)), []string{"a", "b", "c"}},
+		{"invalid int", Get(p, "BAD_INT", 3), 3},
+		{"invalid bool", Get(p, "BAD_BOOL", true), true},
+		{"int32 overflow", Get(p, "BIG", int32(7)), int32(7)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("Get = %#v, want %#v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetRecordsErrors(t *testing.T) {
+	p := NewWithLookup(lookup(map[string]string{"PORT": "8080", "BAD_INT": "ten"}))
+
+	Get(p, "PORT", 0)
+	if err := p.Err(); err != nil {
+		t.Fatalf("Err after valid Get = %v, want nil", err)
+	}
+
+	Get(p, "BAD_INT", 0)
+	Get(p, "BAD_INT", time.Duration(0))
+	if err := p.Err(); err == nil {
+		t.Error("Err after invalid Get = nil, want an error")
+	}
+}
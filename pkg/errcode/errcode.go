@@ -0,0 +1,203 @@
+package errcode
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// gRPC status codes, mirrored here so services can map codes without this
+// package depending on grpc
+const (
+	GRPCOK                 uint32 = 0
+	GRPCInvalidArgument    uint32 = 3
+	GRPCNotFound           uint32 = 5
+	GRPCAlreadyExists      uint32 = 6
+	GRPCPermissionDenied   uint32 = 7
+	GRPCResourceExhausted  uint32 = 8
+	GRPCFailedPrecondition uint32 = 9
+	GRPCInternal           uint32 = 13
+	GRPCUnavailable        uint32 = 14
+	GRPCUnauthenticated    uint32 = 16
+)
+
+// Code is a catalog entry. ID is namespaced as "<namespace>.<name>", e.g.
+// "billing.card_declined", and is what clients branch on.
+type Code struct {
+	ID         string `json:"code"`
+	HTTPStatus int    `json:"-"`
+	GRPCCode   uint32 `json:"-"`
+	MessageKey string `json:"message_key"` // i18n key for the user-facing message
+}
+
+// Registry is a catalog of error codes with duplicate detection
+type Registry struct {
+	mu    sync.RWMutex
+	codes map[string]Code
+}
+
+func NewRegistry() *Registry {
+	return &Registry{codes: make(map[string]Code)}
+}
+
+// Register adds codes under namespace. Each code's ID is the name within the
+// namespace and is rewritten to its fully qualified form.
+func (r *Registry) Register(namespace string, codes ...Code) ([]Code, error) {
+	if namespace == "" || strings.Contains(namespace, ".") {
+		return nil, fmt.Errorf("errcode: invalid namespace %q", namespace)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	qualified := make([]Code, len(codes))
+	for i, c := range codes {
+		c.ID = namespace + "." + c.ID
+		if _, exists := r.codes[c.ID]; exists {
+			return nil, fmt.Errorf("errcode: duplicate code %q", c.ID)
+		}
+		if c.HTTPStatus == 0 {
+			c.HTTPStatus = http.StatusInternalServerError
+		}
+		if c.MessageKey == "" {
+			c.MessageKey = "errors." + c.ID
+		}
+		qualified[i] = c
+	}
+	for _, c := range qualified {
+		r.codes[c.ID] = c
+	}
+	return qualified, nil
+}
+
+// MustRegister is like Register but panics on error, for package-level vars
+func (r *Registry) MustRegister(namespace string, codes ...Code) []Code {
+	qualified, err := r.Register(namespace, codes...)
+	if err != nil {
+		panic(err)
+	}
+	return qualified
+}
+
+// Lookup returns the code registered under id
+func (r *Registry) Lookup(id string) (Code, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.codes[id]
+	return c, ok
+}
+
+// All returns the catalog sorted by ID, e.g. for publishing to clients
+func (r *Registry) All() []Code {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	all := make([]Code, 0, len(r.codes))
+	for _, c := range r.codes {
+		all = append(all, c)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+	return all
+}
+
+// Default is the process-wide registry
+var Default = NewRegistry()
+
+// Common codes shared by every service
+var (
+	common = Default.MustRegister("common",
+		Code{ID: "invalid_argument", HTTPStatus: http.StatusBadRequest, GRPCCode: GRPCInvalidArgument},
+		Code{ID: "unauthenticated", HTTPStatus: http.StatusUnauthorized, GRPCCode: GRPCUnauthenticated},
+		Code{ID: "permission_denied", HTTPStatus: http.StatusForbidden, GRPCCode: GRPCPermissionDenied},
+		Code{ID: "not_found", HTTPStatus: http.StatusNotFound, GRPCCode: GRPCNotFound},
+		Code{ID: "conflict", HTTPStatus: http.StatusConflict, GRPCCode: GRPCAlreadyExists},
+		Code{ID: "rate_limited", HTTPStatus: http.StatusTooManyRequests, GRPCCode: GRPCResourceExhausted},
+		Code{ID: "internal", HTTPStatus: http.StatusInternalServerError, GRPCCode: GRPCInternal},
+		Code{ID: "unavailable", HTTPStatus: http.StatusServiceUnavailable, GRPCCode: GRPCUnavailable},
+	)
+
+	InvalidArgument  = common[0]
+	Unauthenticated  = common[1]
+	PermissionDenied = common[2]
+	NotFound         = common[3]
+	Conflict         = common[4]
+	RateLimited      = common[5]
+	Internal         = common[6]
+	Unavailable      = common[7]
+)
+
+// Error is an error carrying a catalog code. Message is safe to show to
+// clients; the wrapped Err is for logs only and never leaves the service.
+type Error struct {
+	Code    Code
+	Message string
+	Err     error
+}
+
+// New creates an error for code with a client-facing message
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Wrap attaches code to an underlying error. The error's text is not shown
+// to clients; add a client-facing message with WithMessage.
+func Wrap(code Code, err error) *Error {
+	return &Error{Code: code, Err: err}
+}
+
+// WithMessage returns a copy of e with a client-facing message
+func (e *Error) WithMessage(message string) *Error {
+	cp := *e
+	cp.Message = message
+	return &cp
+}
+
+func (e *Error) Error() string {
+	msg := e.Code.ID
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches any *Error with the same code ID
+func (e *Error) Is(target error) bool {
+	var t *Error
+	return errors.As(target, &t) && t.Code.ID == e.Code.ID
+}
+
+// CodeOf returns the code attached to err, or Internal if there is none
+func CodeOf(err error) Code {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return Internal
+}
+
+// Abort writes err as a JSON error response and aborts the request
+func Abort(c *gin.Context, err error) {
+	code := CodeOf(err)
+	body := gin.H{
+		"error":       http.StatusText(code.HTTPStatus),
+		"code":        code.ID,
+		"message_key": code.MessageKey,
+	}
+	var e *Error
+	if errors.As(err, &e) && e.Message != "" && code.HTTPStatus < http.StatusInternalServerError {
+		body["error"] = e.Message
+	}
+	c.AbortWithStatusJSON(code.HTTPStatus, body)
+}
@@ -0,0 +1,186 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Redacted replaces the value of sensitive fields in logs and responses
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are field names whose values are always redacted
+var sensitiveKeys = map[string]bool{
+	"password":      true,
+	"token":         true,
+	"access_token":  true,
+	"refresh_token": true,
+	"secret":        true,
+	"authorization": true,
+	"api_key":       true,
+	"ssn":           true,
+	"card_number":   true,
+}
+
+// Field is a structured key/value attached to an error
+type Field struct {
+	Key       string
+	Value     any
+	Sensitive bool
+}
+
+// Error wraps an underlying error with a message, fields and the stack at the
+// point it was first wrapped
+type Error struct {
+	msg    string
+	err    error
+	fields []Field
+	stack  []uintptr
+}
+
+func (e *Error) Error() string {
+	if e.err == nil {
+		return e.msg
+	}
+	if e.msg == "" {
+		return e.err.Error()
+	}
+	return e.msg + ": " + e.err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// New creates an error with a stack trace
+func New(msg string) error {
+	return &Error{msg: msg, stack: callers(3)}
+}
+
+// Wrap annotates err with msg. The stack is only captured if err doesn't
+// already carry one. Wrap returns nil when err is nil.
+func Wrap(err error, msg string) error {
+	if err == nil {
+		return nil
+	}
+	e := &Error{msg: msg, err: err}
+	if !hasStack(err) {
+		e.stack = callers(3)
+	}
+	return e
+}
+
+// Wrapf is Wrap with a format string
+func Wrapf(err error, format string, args ...any) error {
+	if err == nil {
+		return nil
+	}
+	e := &Error{msg: fmt.Sprintf(format, args...), err: err}
+	if !hasStack(err) {
+		e.stack = callers(3)
+	}
+	return e
+}
+
+// WithField attaches a field to err
+func WithField(err error, key string, value any) error {
+	return withField(err, Field{Key: key, Value: value})
+}
+
+// WithSensitiveField attaches a field whose value is always redacted
+func WithSensitiveField(err error, key string, value any) error {
+	return withField(err, Field{Key: key, Value: value, Sensitive: true})
+}
+
+func withField(err error, f Field) error {
+	if err == nil {
+		return nil
+	}
+	e := &Error{err: err, fields: []Field{f}}
+	if !hasStack(err) {
+		e.stack = callers(4)
+	}
+	return e
+}
+
+// Fields returns every field attached along err's chain, outermost first,
+// with sensitive values redacted
+func Fields(err error) map[string]any {
+	fields := make(map[string]any)
+	for err != nil {
+		if e, ok := err.(*Error); ok {
+			for _, f := range e.fields {
+				if _, exists := fields[f.Key]; exists {
+					continue
+				}
+				if f.Sensitive || sensitiveKeys[strings.ToLower(f.Key)] {
+					fields[f.Key] = Redacted
+				} else {
+					fields[f.Key] = f.Value
+				}
+			}
+		}
+		err = errors.Unwrap(err)
+	}
+	return fields
+}
+
+// Stack returns the formatted stack trace captured for err, if any
+func Stack(err error) string {
+	var e *Error
+	for errors.As(err, &e) {
+		if len(e.stack) > 0 {
+			return formatStack(e.stack)
+		}
+		err = e.err
+	}
+	return ""
+}
+
+// ZapFields converts err into logger fields: the error, its stack and every
+// attached field
+func ZapFields(err error) []zap.Field {
+	zf := []zap.Field{zap.Error(err)}
+	if stack := Stack(err); stack != "" {
+		zf = append(zf, zap.String("stack", stack))
+	}
+	for k, v := range Fields(err) {
+		zf = append(zf, zap.Any(k, v))
+	}
+	return zf
+}
+
+func hasStack(err error) bool {
+	var e *Error
+	for errors.As(err, &e) {
+		if len(e.stack) > 0 {
+			return true
+		}
+		err = e.err
+	}
+	return false
+}
+
+// callers captures the stack, skipping runtime.Callers, callers itself and
+// the package functions that led here
+func callers(skip int) []uintptr {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(skip, pcs)
+	return pcs[:n]
+}
+
+func formatStack(pcs []uintptr) string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
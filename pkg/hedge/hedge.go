@@ -0,0 +1,167 @@
+// Package hedge sends hedged HTTP requests: if the first attempt hasn't
+// answered within a delay, a second identical attempt is sent and whichever
+// responds first wins, cancelling the other. Only idempotent requests are
+// hedged, since the upstream may see every attempt.
+package hedge
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+type Config struct {
+	Delay       time.Duration // Wait before sending each additional attempt
+	MaxAttempts int           // Total attempts including the first, 2 for a single hedge
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Delay:       100 * time.Millisecond,
+		MaxAttempts: 2,
+	}
+}
+
+// Transport returns a RoundTripper hedging GET, HEAD and OPTIONS requests
+// sent through base (http.DefaultTransport when nil). Other requests, and
+// requests whose body can't be replayed, are passed through unchanged. The
+// first response wins whatever its status; a failed attempt immediately
+// starts the next one.
+func Transport(base http.RoundTripper, config *Config) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if config == nil {
+		config = DefaultConfig()
+	}
+	return &transport{base: base, config: config}
+}
+
+type transport struct {
+	base   http.RoundTripper
+	config *Config
+}
+
+type result struct {
+	attempt int
+	resp    *http.Response
+	err     error
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.config.MaxAttempts < 2 || !hedgeable(req) {
+		return t.base.RoundTrip(req)
+	}
+	if req.Body != nil {
+		// Every attempt sends its own copy from GetBody
+		req.Body.Close()
+	}
+
+	results := make(chan result, t.config.MaxAttempts)
+	var cancels []context.CancelFunc
+	launch := func() error {
+		ctx, cancel := context.WithCancel(req.Context())
+		attempt := req.Clone(ctx)
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				cancel()
+				return err
+			}
+			attempt.Body = body
+		}
+
+		i := len(cancels)
+		cancels = append(cancels, cancel)
+		go func() {
+			resp, err := t.base.RoundTrip(attempt)
+			results <- result{attempt: i, resp: resp, err: err}
+		}()
+		return nil
+	}
+
+	// discard cancels every attempt but keep (-1 for none) and closes any
+	// response the pending ones still return
+	discard := func(keep, pending int) {
+		for i, cancel := range cancels {
+			if i != keep {
+				cancel()
+			}
+		}
+		go func() {
+			for i := 0; i < pending; i++ {
+				if r := <-results; r.resp != nil {
+					r.resp.Body.Close()
+				}
+			}
+		}()
+	}
+
+	if err := launch(); err != nil {
+		return nil, err
+	}
+	pending := 1
+
+	timer := time.NewTimer(t.config.Delay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			if len(cancels) < t.config.MaxAttempts {
+				if err := launch(); err == nil {
+					pending++
+				}
+				if len(cancels) < t.config.MaxAttempts {
+					timer.Reset(t.config.Delay)
+				}
+			}
+
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				discard(r.attempt, pending)
+				// The winner's context must outlive RoundTrip until its body is read
+				r.resp.Body = &cancelOnClose{ReadCloser: r.resp.Body, cancel: cancels[r.attempt]}
+				return r.resp, nil
+			}
+
+			cancels[r.attempt]()
+			if len(cancels) < t.config.MaxAttempts {
+				if err := launch(); err == nil {
+					pending++
+				}
+			}
+			if pending == 0 {
+				return nil, r.err
+			}
+
+		case <-req.Context().Done():
+			discard(-1, pending)
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// hedgeable reports whether req is idempotent and can be sent more than once
+func hedgeable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// cancelOnClose releases the winning attempt's context once its body is done
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
@@ -0,0 +1,181 @@
+package hedge
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// trackedBody records whether a response body was closed
+type trackedBody struct {
+	io.Reader
+	once   sync.Once
+	closed chan struct{}
+}
+
+func newBody(s string) *trackedBody {
+	return &trackedBody{Reader: strings.NewReader(s), closed: make(chan struct{})}
+}
+
+func (b *trackedBody) Close() error {
+	b.once.Do(func() { close(b.closed) })
+	return nil
+}
+
+func response(body io.ReadCloser) *http.Response {
+	return &http.Response{StatusCode: http.StatusOK, Body: body}
+}
+
+func waitClosed(t *testing.T, name string, b *trackedBody) {
+	t.Helper()
+	select {
+	case <-b.closed:
+	case <-time.After(time.Second):
+		t.Errorf("%s body was not closed", name)
+	}
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	return string(b)
+}
+
+func TestFirstAttemptWins(t *testing.T) {
+	var calls atomic.Int32
+	rt := Transport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls.Add(1)
+		return response(newBody("first")), nil
+	}), &Config{Delay: time.Second, MaxAttempts: 2})
+
+	resp, err := rt.RoundTrip(httptest.NewRequest(http.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatalf("RoundTrip: %v", err)
+	}
+	if got := readBody(t, resp); got != "first" {
+		t.Errorf("body = %q, want %q", got, "first")
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("attempts = %d, want 1", n)
+	}
+}
+
+func TestHedgeWins(t *testing.T) {
+	var calls atomic.Int32
+	slow := newBody("first")
+	rt := Transport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if calls.Add(1) == 1 {
+			// Answers only once the hedge has won and cancelled it
+			<-r.Context().Done()
+			return response(slow), nil
+		}
+		return response(newBody("hedge")), nil
+	}), &Config{Delay: 10 * time.Millisecond, MaxAttempts: 2})
+
+	resp, err := rt.RoundTrip(httptest.NewRequest(http.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatalf("RoundTrip: %v", err)
+	}
+	if got := readBody(t, resp); got != "hedge" {
+		t.Errorf("body = %q, want %q", got, "hedge")
+	}
+	waitClosed(t, "losing", slow)
+}
+
+func TestAllAttemptsFail(t *testing.T) {
+	errUpstream := errors.New("upstream down")
+	var calls atomic.Int32
+	rt := Transport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls.Add(1)
+		return nil, errUpstream
+	}), &Config{Delay: time.Second, MaxAttempts: 3})
+
+	_, err := rt.RoundTrip(httptest.NewRequest(http.MethodGet, "/", nil))
+	if !errors.Is(err, errUpstream) {
+		t.Errorf("RoundTrip = %v, want %v", err, errUpstream)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("attempts = %d, want 3", n)
+	}
+}
+
+func TestParentContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var bodies []*trackedBody
+	started := make(chan struct{}, 2)
+	rt := Transport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		b := newBody("late")
+		mu.Lock()
+		bodies = append(bodies, b)
+		mu.Unlock()
+		started <- struct{}{}
+
+		<-r.Context().Done()
+		return response(b), nil
+	}), &Config{Delay: time.Millisecond, MaxAttempts: 2})
+
+	go func() {
+		<-started
+		<-started
+		cancel()
+	}()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	if _, err := rt.RoundTrip(req); !errors.Is(err, context.Canceled) {
+		t.Fatalf("RoundTrip = %v, want %v", err, context.Canceled)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, b := range bodies {
+		waitClosed(t, "abandoned", b)
+	}
+}
+
+func TestPassesThroughUnhedgeable(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *http.Request
+	}{
+		{"post", httptest.NewRequest(http.MethodPost, "/", strings.NewReader("x"))},
+		{"unreplayable body", &http.Request{Method: http.MethodGet, Body: io.NopCloser(strings.NewReader("x"))}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			rt := Transport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				calls.Add(1)
+				time.Sleep(5 * time.Millisecond)
+				return response(newBody("ok")), nil
+			}), &Config{Delay: time.Millisecond, MaxAttempts: 2})
+
+			resp, err := rt.RoundTrip(tt.req)
+			if err != nil {
+				t.Fatalf("RoundTrip: %v", err)
+			}
+			resp.Body.Close()
+			if n := calls.Load(); n != 1 {
+				t.Errorf("attempts = %d, want 1", n)
+			}
+		})
+	}
+}
@@ -100,6 +100,11 @@ func (l *zapLogger) With(fields ...zap.Field) Logger {
 	return &zapLogger{l.Logger.With(fields...)}
 }
 
+// NewNop returns a Logger that discards everything
+func NewNop() Logger {
+	return &zapLogger{zap.NewNop()}
+}
+
 // Example function to create a development logger quickly
 func NewDevelopment() (Logger, error) {
 	cfg := &Config{
@@ -0,0 +1,135 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// ErrOutsideWindow is returned when heavy work is attempted outside an allowed window
+var ErrOutsideWindow = errors.New("maintenance: outside allowed window")
+
+// Window is a recurring period when disruptive work may run, e.g. Sundays
+// from 02:00 for 3 hours in UTC. An empty Days list means every day.
+type Window struct {
+	Days     []time.Weekday
+	Start    time.Duration // Offset from midnight
+	Duration time.Duration
+	Location *time.Location
+}
+
+// contains reports whether t falls inside this window, including a window
+// that started the previous day and runs past midnight
+func (w Window) contains(t time.Time) bool {
+	loc := w.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+
+	for _, dayOffset := range []int{0, -1} {
+		day := t.AddDate(0, 0, dayOffset)
+		if !w.onDay(day.Weekday()) {
+			continue
+		}
+		midnight := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+		start := midnight.Add(w.Start)
+		if !t.Before(start) && t.Before(start.Add(w.Duration)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w Window) onDay(d time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, day := range w.Days {
+		if day == d {
+			return true
+		}
+	}
+	return false
+}
+
+// Schedule maps environments to their maintenance windows. Environments
+// without an entry are unrestricted.
+type Schedule struct {
+	windows  map[string][]Window
+	override atomic.Bool // Toggled at runtime while checks run concurrently
+}
+
+// NewSchedule creates a schedule. Setting MAINTENANCE_OVERRIDE=true in the
+// environment bypasses every window for emergencies.
+func NewSchedule(windows map[string][]Window) *Schedule {
+	override, _ := strconv.ParseBool(os.Getenv("MAINTENANCE_OVERRIDE"))
+	s := &Schedule{windows: windows}
+	s.override.Store(override)
+	return s
+}
+
+// SetOverride forces every check to pass, e.g. from an --emergency flag
+func (s *Schedule) SetOverride(override bool) {
+	s.override.Store(override)
+}
+
+// Allowed reports whether disruptive work may run in env at t
+func (s *Schedule) Allowed(env string, t time.Time) bool {
+	if s.override.Load() {
+		return true
+	}
+	windows, restricted := s.windows[env]
+	if !restricted {
+		return true
+	}
+	for _, w := range windows {
+		if w.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns ErrOutsideWindow when work isn't allowed in env right now
+func (s *Schedule) Check(env string) error {
+	if !s.Allowed(env, time.Now()) {
+		return fmt.Errorf("%w for %s", ErrOutsideWindow, env)
+	}
+	return nil
+}
+
+// Next returns the start of the next allowed window at or after t, searching
+// up to a week ahead at minute granularity
+func (s *Schedule) Next(env string, t time.Time) (time.Time, bool) {
+	t = t.Truncate(time.Minute)
+	for end := t.Add(7 * 24 * time.Hour); t.Before(end); t = t.Add(time.Minute) {
+		if s.Allowed(env, t) {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Wait blocks until a window opens for env or ctx is cancelled, letting
+// scheduled jobs defer themselves instead of failing
+func (s *Schedule) Wait(ctx context.Context, env string) error {
+	next, ok := s.Next(env, time.Now())
+	if !ok {
+		return fmt.Errorf("%w for %s: no window in the next week", ErrOutsideWindow, env)
+	}
+
+	timer := time.NewTimer(time.Until(next))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,74 @@
+// Package dbsession derives per-request Postgres settings (tenant GUC for row
+// level security, statement timeout, application_name). They only take
+// effect in transactions started through connection.WithTx,
+// connection.InSession or connection.Coordinate.
+package dbsession
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ranson21/ranor-common/pkg/database/connection"
+)
+
+// Deriver returns the database session settings for a request
+type Deriver func(c *gin.Context) connection.SessionSettings
+
+// Tenant sets the GUC read by row level security policies to the request's tenant
+func Tenant(guc string, tenantID func(*gin.Context) string) Deriver {
+	return func(c *gin.Context) connection.SessionSettings {
+		id := tenantID(c)
+		if id == "" {
+			return nil
+		}
+		return connection.SessionSettings{guc: id}
+	}
+}
+
+// RouteTimeout sets statement_timeout from the route class of the request,
+// e.g. a longer limit for reporting routes. Routes not in classes keep the
+// connection default.
+func RouteTimeout(classify func(*gin.Context) string, timeouts map[string]string) Deriver {
+	return func(c *gin.Context) connection.SessionSettings {
+		timeout, ok := timeouts[classify(c)]
+		if !ok {
+			return nil
+		}
+		return connection.SessionSettings{"statement_timeout": timeout}
+	}
+}
+
+// ApplicationName tags queries with the service and matched route so they can
+// be attributed in pg_stat_activity
+func ApplicationName(service string) Deriver {
+	return func(c *gin.Context) connection.SessionSettings {
+		name := service
+		if route := c.FullPath(); route != "" {
+			name += " " + c.Request.Method + " " + route
+		}
+		// Postgres truncates application_name to 63 bytes
+		if len(name) > 63 {
+			name = name[:63]
+		}
+		return connection.SessionSettings{"application_name": name}
+	}
+}
+
+// Middleware attaches the settings derived for each request to its context.
+// Only connection.WithTx, connection.InSession and connection.Coordinate
+// apply them (with SET LOCAL); queries made directly on a pool or on
+// GetSQLDB run without them, so RLS-scoped handlers must use those helpers.
+func Middleware(derivers ...Deriver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		settings := make(connection.SessionSettings)
+		for _, derive := range derivers {
+			for name, value := range derive(c) {
+				settings[name] = value
+			}
+		}
+
+		if len(settings) > 0 {
+			ctx := connection.WithSessionSettings(c.Request.Context(), settings)
+			c.Request = c.Request.WithContext(ctx)
+		}
+		c.Next()
+	}
+}
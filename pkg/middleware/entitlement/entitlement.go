@@ -0,0 +1,173 @@
+package entitlement
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Plan maps a subscription plan to the features and limits it includes
+type Plan struct {
+	Name     string
+	Features map[string]bool
+	Limits   map[string]int64
+}
+
+// Allows reports whether the plan includes feature
+func (p *Plan) Allows(feature string) bool {
+	return p != nil && p.Features[feature]
+}
+
+// PlanSource resolves a tenant's current plan (billing service, config, database)
+type PlanSource interface {
+	PlanFor(ctx context.Context, tenantID string) (*Plan, error)
+}
+
+type cacheEntry struct {
+	plan    *Plan
+	expires time.Time
+}
+
+// Checker caches plan lookups and builds entitlement middleware
+type Checker struct {
+	source   PlanSource
+	tenantID func(*gin.Context) string
+	ttl      time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+func NewChecker(source PlanSource, tenantID func(*gin.Context) string, ttl time.Duration) *Checker {
+	return &Checker{
+		source:   source,
+		tenantID: tenantID,
+		ttl:      ttl,
+		cache:    make(map[string]cacheEntry),
+	}
+}
+
+// Plan returns the tenant's plan, served from cache when fresh
+func (ch *Checker) Plan(ctx context.Context, tenantID string) (*Plan, error) {
+	ch.mu.Lock()
+	entry, exists := ch.cache[tenantID]
+	ch.mu.Unlock()
+
+	if exists && time.Now().Before(entry.expires) {
+		return entry.plan, nil
+	}
+
+	plan, err := ch.source.PlanFor(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	ch.mu.Lock()
+	ch.cache[tenantID] = cacheEntry{plan: plan, expires: time.Now().Add(ch.ttl)}
+	ch.mu.Unlock()
+
+	return plan, nil
+}
+
+// Invalidate drops the cached plan for a tenant, e.g. after a plan change
+func (ch *Checker) Invalidate(tenantID string) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	delete(ch.cache, tenantID)
+}
+
+// Require aborts with 402 unless the tenant's plan includes feature
+func (ch *Checker) Require(feature string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		plan, ok := ch.resolve(c)
+		if !ok {
+			return
+		}
+
+		if !plan.Allows(feature) {
+			paymentRequired(c, plan, gin.H{
+				"error":   "Feature not included in plan",
+				"code":    "feature_not_entitled",
+				"feature": feature,
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// Limit aborts with 402 when usage reported for the tenant has reached the
+// plan's limit for name. Plans without that limit are unrestricted.
+func (ch *Checker) Limit(name string, usage func(*gin.Context) (int64, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		plan, ok := ch.resolve(c)
+		if !ok {
+			return
+		}
+
+		// No plan entitles nothing, as in Require
+		if plan == nil {
+			paymentRequired(c, nil, gin.H{
+				"error": "No active plan",
+				"code":  "plan_limit_reached",
+				"limit": name,
+			})
+			return
+		}
+
+		limit, limited := plan.Limits[name]
+		if !limited {
+			c.Next()
+			return
+		}
+
+		used, err := usage(c)
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Unable to verify plan usage"})
+			c.Abort()
+			return
+		}
+
+		if used >= limit {
+			paymentRequired(c, plan, gin.H{
+				"error": "Plan limit reached",
+				"code":  "plan_limit_reached",
+				"limit": name,
+				"max":   limit,
+				"used":  used,
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func (ch *Checker) resolve(c *gin.Context) (*Plan, bool) {
+	tenantID := ch.tenantID(c)
+	if tenantID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Abort()
+		return nil, false
+	}
+
+	plan, err := ch.Plan(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Unable to resolve subscription plan"})
+		c.Abort()
+		return nil, false
+	}
+	return plan, true
+}
+
+func paymentRequired(c *gin.Context, plan *Plan, body gin.H) {
+	if plan != nil {
+		body["plan"] = plan.Name
+	}
+	c.JSON(http.StatusPaymentRequired, body)
+	c.Abort()
+}
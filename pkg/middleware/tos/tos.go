@@ -0,0 +1,223 @@
+package tos
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ranson21/ranor-common/pkg/consent"
+	"golang.org/x/sync/singleflight"
+)
+
+// Source resolves the current terms version and what a user has accepted.
+// AcceptedVersion returns 0 when the user has never accepted.
+type Source interface {
+	LatestVersion(ctx context.Context) (int, error)
+	AcceptedVersion(ctx context.Context, userID string) (int, error)
+}
+
+type Config struct {
+	Source    Source
+	UserID    func(*gin.Context) string // Returns "" for unauthenticated requests
+	Exempt    []string                  // Path prefixes (whole segments) that skip the check, e.g. the acceptance endpoints
+	AcceptURL string                    // Where clients should send the user to accept
+	Status    int                       // 409 or 451, anything else means 409
+	CacheTTL  time.Duration
+}
+
+func DefaultConfig(source Source, userID func(*gin.Context) string) *Config {
+	return &Config{
+		Source:    source,
+		UserID:    userID,
+		AcceptURL: "/terms/accept",
+		Exempt:    []string{"/terms"},
+		Status:    http.StatusConflict,
+		CacheTTL:  5 * time.Minute,
+	}
+}
+
+type cacheEntry struct {
+	version int
+	expires time.Time
+}
+
+// versionCache caches the latest version and each user's accepted version.
+// Lookups happen outside the lock and concurrent misses for the same key
+// share one query.
+type versionCache struct {
+	source Source
+	ttl    time.Duration
+	group  singleflight.Group
+
+	mu        sync.RWMutex
+	latest    cacheEntry
+	accepted  map[string]cacheEntry
+	lastSweep time.Time
+}
+
+func (vc *versionCache) latestVersion(ctx context.Context) (int, error) {
+	vc.mu.RLock()
+	entry := vc.latest
+	vc.mu.RUnlock()
+	if time.Now().Before(entry.expires) {
+		return entry.version, nil
+	}
+
+	v, err, _ := vc.group.Do("latest", func() (any, error) {
+		// Shared by every waiting request, so don't let one cancellation fail them all
+		v, err := vc.source.LatestVersion(context.WithoutCancel(ctx))
+		if err != nil {
+			return 0, err
+		}
+		vc.mu.Lock()
+		vc.latest = cacheEntry{version: v, expires: time.Now().Add(vc.ttl)}
+		vc.mu.Unlock()
+		return v, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return v.(int), nil
+}
+
+func (vc *versionCache) acceptedVersion(ctx context.Context, userID string, latest int) (int, error) {
+	vc.mu.RLock()
+	entry, exists := vc.accepted[userID]
+	vc.mu.RUnlock()
+	if exists && time.Now().Before(entry.expires) && entry.version >= latest {
+		return entry.version, nil
+	}
+
+	v, err, _ := vc.group.Do("user:"+userID, func() (any, error) {
+		v, err := vc.source.AcceptedVersion(context.WithoutCancel(ctx), userID)
+		if err != nil {
+			return 0, err
+		}
+		now := time.Now()
+		vc.mu.Lock()
+		vc.accepted[userID] = cacheEntry{version: v, expires: now.Add(vc.ttl)}
+		vc.sweep(now)
+		vc.mu.Unlock()
+		return v, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return v.(int), nil
+}
+
+// sweep drops expired entries at most once per TTL. Callers hold mu.
+func (vc *versionCache) sweep(now time.Time) {
+	if now.Sub(vc.lastSweep) < vc.ttl {
+		return
+	}
+	vc.lastSweep = now
+	for userID, entry := range vc.accepted {
+		if now.After(entry.expires) {
+			delete(vc.accepted, userID)
+		}
+	}
+}
+
+// TermsGate rejects authenticated requests from users who haven't accepted the
+// latest terms of service version.
+func TermsGate(config *Config) gin.HandlerFunc {
+	status := config.Status
+	if status != http.StatusConflict && status != http.StatusUnavailableForLegalReasons {
+		status = http.StatusConflict
+	}
+
+	cache := &versionCache{
+		source:   config.Source,
+		ttl:      config.CacheTTL,
+		accepted: make(map[string]cacheEntry),
+	}
+
+	lookup := func(ctx context.Context, userID string) (int, int, error) {
+		latest, err := cache.latestVersion(ctx)
+		if err != nil {
+			return 0, 0, err
+		}
+		accepted, err := cache.acceptedVersion(ctx, userID, latest)
+		if err != nil {
+			return 0, 0, err
+		}
+		return latest, accepted, nil
+	}
+
+	return func(c *gin.Context) {
+		for _, prefix := range config.Exempt {
+			if underPath(c.Request.URL.Path, prefix) {
+				c.Next()
+				return
+			}
+		}
+
+		userID := config.UserID(c)
+		if userID == "" {
+			c.Next()
+			return
+		}
+
+		required, acceptedVersion, err := lookup(c.Request.Context(), userID)
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Unable to verify terms acceptance"})
+			c.Abort()
+			return
+		}
+
+		if acceptedVersion < required {
+			c.JSON(status, gin.H{
+				"error":            "Terms of service not accepted",
+				"code":             "terms_not_accepted",
+				"required_version": required,
+				"accepted_version": acceptedVersion,
+				"accept_url":       config.AcceptURL,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// underPath reports whether path is prefix or below it, so "/terms" matches
+// "/terms/accept" but not "/termsfoo"
+func underPath(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// ConsentSource reads terms acceptance from the consent store using a policy kind
+type ConsentSource struct {
+	Store *consent.Store
+	Kind  string
+}
+
+func (s ConsentSource) LatestVersion(ctx context.Context) (int, error) {
+	p, err := s.Store.LatestPolicy(ctx, s.Kind)
+	if errors.Is(err, consent.ErrPolicyNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return p.Version, nil
+}
+
+func (s ConsentSource) AcceptedVersion(ctx context.Context, userID string) (int, error) {
+	consents, err := s.Store.Current(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	c, ok := consents[s.Kind]
+	if !ok || !c.Active() {
+		return 0, nil
+	}
+	return c.PolicyVersion, nil
+}
@@ -0,0 +1,45 @@
+package org
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MembershipKey is the gin context key holding the caller's *Membership
+const MembershipKey = "OrgMembership"
+
+// RequireRole aborts the request unless the authenticated user holds at least
+// min in the organization identified by orgID.
+func RequireRole(store *Store, orgID, userID func(*gin.Context) string, min Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid := userID(c)
+		if uid == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			c.Abort()
+			return
+		}
+
+		m, err := store.Membership(c.Request.Context(), orgID(c), uid)
+		if errors.Is(err, ErrNotFound) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this organization"})
+			c.Abort()
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			c.Abort()
+			return
+		}
+
+		if !m.Role.AtLeast(min) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient role"})
+			c.Abort()
+			return
+		}
+
+		c.Set(MembershipKey, m)
+		c.Next()
+	}
+}
@@ -0,0 +1,324 @@
+package org
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Role is a member's role within an organization
+type Role string
+
+const (
+	Owner  Role = "owner"
+	Admin  Role = "admin"
+	Member Role = "member"
+)
+
+var roleRank = map[Role]int{
+	Member: 1,
+	Admin:  2,
+	Owner:  3,
+}
+
+// AtLeast reports whether r grants at least the permissions of min
+func (r Role) AtLeast(min Role) bool {
+	return roleRank[r] >= roleRank[min]
+}
+
+var (
+	ErrNotFound          = errors.New("org: not found")
+	ErrInvalidRole       = errors.New("org: invalid role")
+	ErrInvitationExpired = errors.New("org: invitation expired or already used")
+	ErrForbidden         = errors.New("org: actor's role is too low")
+	ErrLastOwner         = errors.New("org: organization must keep an owner")
+)
+
+type Organization struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Membership struct {
+	OrgID     string    `json:"org_id"`
+	UserID    string    `json:"user_id"`
+	Role      Role      `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Invitation struct {
+	ID        string    `json:"id"`
+	OrgID     string    `json:"org_id"`
+	Email     string    `json:"email"`
+	Role      Role      `json:"role"`
+	InvitedBy string    `json:"invited_by"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Store persists organizations, memberships and invitations
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+func NewStore(pool *pgxpool.Pool) *Store {
+	return &Store{pool: pool}
+}
+
+// EnsureSchema creates the organization tables if they don't exist
+func (s *Store) EnsureSchema(ctx context.Context) error {
+	_, err := s.pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS organizations (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			name TEXT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+		CREATE TABLE IF NOT EXISTS memberships (
+			org_id UUID NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+			user_id TEXT NOT NULL,
+			role TEXT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			PRIMARY KEY (org_id, user_id)
+		);
+		CREATE INDEX IF NOT EXISTS memberships_user_idx ON memberships (user_id);
+		CREATE TABLE IF NOT EXISTS invitations (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			org_id UUID NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+			email TEXT NOT NULL,
+			role TEXT NOT NULL,
+			invited_by TEXT NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE,
+			expires_at TIMESTAMPTZ NOT NULL,
+			accepted_at TIMESTAMPTZ
+		);`)
+	if err != nil {
+		return fmt.Errorf("error creating organization schema: %w", err)
+	}
+	return nil
+}
+
+// CreateOrganization creates an organization with ownerID as its first owner
+func (s *Store) CreateOrganization(ctx context.Context, name, ownerID string) (*Organization, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	o := &Organization{Name: name}
+	err = tx.QueryRow(ctx,
+		"INSERT INTO organizations (name) VALUES ($1) RETURNING id::text, created_at", name,
+	).Scan(&o.ID, &o.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("error creating organization: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx,
+		"INSERT INTO memberships (org_id, user_id, role) VALUES ($1, $2, $3)", o.ID, ownerID, Owner,
+	); err != nil {
+		return nil, fmt.Errorf("error adding owner: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("error committing organization: %w", err)
+	}
+	return o, nil
+}
+
+// SetRole adds the user to the organization or changes their role on behalf
+// of a member holding actor (e.g. from the Membership set by RequireRole).
+// The actor can't grant a role above their own or change a member who
+// outranks them, and the last owner can't be demoted.
+func (s *Store) SetRole(ctx context.Context, orgID string, actor Role, userID string, role Role) error {
+	if _, ok := roleRank[role]; !ok {
+		return ErrInvalidRole
+	}
+	if !actor.AtLeast(role) {
+		return ErrForbidden
+	}
+
+	return s.changeMember(ctx, orgID, actor, userID, role != Owner, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO memberships (org_id, user_id, role) VALUES ($1, $2, $3)
+			ON CONFLICT (org_id, user_id) DO UPDATE SET role = EXCLUDED.role`,
+			orgID, userID, role)
+		if err != nil {
+			return fmt.Errorf("error setting role: %w", err)
+		}
+		return nil
+	})
+}
+
+// RemoveMember removes the user from the organization on behalf of a member
+// holding actor. Members who outrank the actor and the last owner can't be
+// removed.
+func (s *Store) RemoveMember(ctx context.Context, orgID string, actor Role, userID string) error {
+	return s.changeMember(ctx, orgID, actor, userID, true, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, "DELETE FROM memberships WHERE org_id = $1 AND user_id = $2", orgID, userID)
+		if err != nil {
+			return fmt.Errorf("error removing member: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+// changeMember runs change in a transaction after checking that actor may
+// modify userID and, when losesOwner is set, that an owner would remain.
+// Owner rows are locked so concurrent changes can't remove every owner.
+func (s *Store) changeMember(ctx context.Context, orgID string, actor Role, userID string, losesOwner bool, change func(pgx.Tx) error) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx,
+		"SELECT user_id FROM memberships WHERE org_id = $1 AND role = $2 FOR UPDATE", orgID, Owner)
+	if err != nil {
+		return fmt.Errorf("error loading owners: %w", err)
+	}
+	owners, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("error loading owners: %w", err)
+	}
+
+	var current Role
+	err = tx.QueryRow(ctx,
+		"SELECT role FROM memberships WHERE org_id = $1 AND user_id = $2 FOR UPDATE", orgID, userID,
+	).Scan(&current)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("error loading membership: %w", err)
+	}
+	if current != "" && !actor.AtLeast(current) {
+		return ErrForbidden
+	}
+	if losesOwner && current == Owner && len(owners) <= 1 {
+		return ErrLastOwner
+	}
+
+	if err := change(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing membership change: %w", err)
+	}
+	return nil
+}
+
+// Membership returns the user's membership in the organization
+func (s *Store) Membership(ctx context.Context, orgID, userID string) (*Membership, error) {
+	m := &Membership{OrgID: orgID, UserID: userID}
+	err := s.pool.QueryRow(ctx,
+		"SELECT role, created_at FROM memberships WHERE org_id = $1 AND user_id = $2", orgID, userID,
+	).Scan(&m.Role, &m.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error loading membership: %w", err)
+	}
+	return m, nil
+}
+
+// Members lists every member of the organization
+func (s *Store) Members(ctx context.Context, orgID string) ([]Membership, error) {
+	rows, err := s.pool.Query(ctx,
+		"SELECT org_id::text, user_id, role, created_at FROM memberships WHERE org_id = $1 ORDER BY created_at", orgID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading members: %w", err)
+	}
+	members, err := pgx.CollectRows(rows, pgx.RowToStructByPos[Membership])
+	if err != nil {
+		return nil, fmt.Errorf("error loading members: %w", err)
+	}
+	return members, nil
+}
+
+// Invite creates an invitation and returns it with the plaintext token to
+// deliver to the invitee. Only a hash of the token is stored. The inviter,
+// holding actor, can't invite to a role above their own.
+func (s *Store) Invite(ctx context.Context, orgID string, actor Role, email string, role Role, invitedBy string, ttl time.Duration) (*Invitation, string, error) {
+	if _, ok := roleRank[role]; !ok {
+		return nil, "", ErrInvalidRole
+	}
+	if !actor.AtLeast(role) {
+		return nil, "", ErrForbidden
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", fmt.Errorf("error generating invitation token: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+
+	inv := &Invitation{OrgID: orgID, Email: email, Role: role, InvitedBy: invitedBy}
+	err := s.pool.QueryRow(ctx, `
+		INSERT INTO invitations (org_id, email, role, invited_by, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id::text, expires_at`,
+		orgID, email, role, invitedBy, hashToken(token), time.Now().Add(ttl),
+	).Scan(&inv.ID, &inv.ExpiresAt)
+	if err != nil {
+		return nil, "", fmt.Errorf("error creating invitation: %w", err)
+	}
+	return inv, token, nil
+}
+
+// AcceptInvitation redeems a token and makes userID a member with the invited role
+func (s *Store) AcceptInvitation(ctx context.Context, token, userID string) (*Membership, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	m := &Membership{UserID: userID}
+	var current Role
+	err = tx.QueryRow(ctx, `
+		UPDATE invitations SET accepted_at = now()
+		WHERE token_hash = $1 AND accepted_at IS NULL AND expires_at > now()
+		RETURNING org_id::text, role`, hashToken(token),
+	).Scan(&m.OrgID, &m.Role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInvitationExpired
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error accepting invitation: %w", err)
+	}
+
+	// Accepting never demotes an existing member, e.g. an owner invited as member
+	err = tx.QueryRow(ctx, `
+		INSERT INTO memberships (org_id, user_id, role) VALUES ($1, $2, $3)
+		ON CONFLICT (org_id, user_id) DO UPDATE SET org_id = memberships.org_id
+		RETURNING role, created_at`, m.OrgID, userID, m.Role,
+	).Scan(&current, &m.CreatedAt)
+	if err == nil && !current.AtLeast(m.Role) {
+		err = tx.QueryRow(ctx,
+			"UPDATE memberships SET role = $3 WHERE org_id = $1 AND user_id = $2 RETURNING role",
+			m.OrgID, userID, m.Role,
+		).Scan(&current)
+	}
+	m.Role = current
+	if err != nil {
+		return nil, fmt.Errorf("error adding member: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("error committing invitation: %w", err)
+	}
+	return m, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,55 @@
+package presence
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes mounts the presence API on r:
+//
+//	GET  /presence/:channel         users currently online
+//	POST /presence/:channel/beat    heartbeat for the current user
+//	GET  /presence/:channel/events  server-sent join/leave events
+func (t *Tracker) RegisterRoutes(r gin.IRoutes, userID func(*gin.Context) string) {
+	// Presence reveals who is online, so every route requires a user
+	authenticated := func(c *gin.Context) {
+		if userID(c) == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+
+	r.GET("/presence/:channel", authenticated, func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"channel": c.Param("channel"),
+			"users":   t.Online(c.Param("channel")),
+		})
+	})
+
+	r.POST("/presence/:channel/beat", authenticated, func(c *gin.Context) {
+		t.Heartbeat(c.Param("channel"), userID(c))
+		c.Status(http.StatusNoContent)
+	})
+
+	r.GET("/presence/:channel/events", authenticated, func(c *gin.Context) {
+		events, stop := t.Subscribe(c.Param("channel"))
+		defer stop()
+
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case <-c.Request.Context().Done():
+				return false
+			case e, ok := <-events:
+				if !ok {
+					return false
+				}
+				c.SSEvent(string(e.Type), e)
+				return true
+			}
+		})
+	})
+}
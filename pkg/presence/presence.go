@@ -0,0 +1,170 @@
+// Package presence tracks which users are online per channel.
+//
+// State lives in the memory of a single process: with several replicas each
+// instance only sees the users heartbeating to it, and subscribers only get
+// that instance's events. Run it on one instance (or route each channel to
+// one instance with sticky sessions) until a shared store backs it.
+package presence
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// EventType is the kind of presence change
+type EventType string
+
+const (
+	Join  EventType = "join"
+	Leave EventType = "leave"
+)
+
+// Event is broadcast to channel subscribers when a user joins or leaves
+type Event struct {
+	Type    EventType `json:"type"`
+	Channel string    `json:"channel"`
+	UserID  string    `json:"user_id"`
+	At      time.Time `json:"at"`
+}
+
+// DefaultTTL is used when NewTracker is given a TTL of zero or less
+const DefaultTTL = 30 * time.Second
+
+// Tracker keeps the set of online users per channel in this process only
+// (see the package doc). Users must heartbeat more often than the TTL or they
+// are considered gone.
+type Tracker struct {
+	ttl time.Duration
+
+	mu          sync.Mutex
+	channels    map[string]map[string]time.Time
+	subscribers map[string]map[chan Event]struct{}
+}
+
+func NewTracker(ttl time.Duration) *Tracker {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Tracker{
+		ttl:         ttl,
+		channels:    make(map[string]map[string]time.Time),
+		subscribers: make(map[string]map[chan Event]struct{}),
+	}
+}
+
+// Heartbeat marks the user online in the channel, broadcasting a join if
+// they weren't already present
+func (t *Tracker) Heartbeat(channel, userID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	users, exists := t.channels[channel]
+	if !exists {
+		users = make(map[string]time.Time)
+		t.channels[channel] = users
+	}
+
+	_, present := users[userID]
+	users[userID] = time.Now()
+	if !present {
+		t.broadcast(Event{Type: Join, Channel: channel, UserID: userID, At: time.Now()})
+	}
+}
+
+// Leave removes the user from the channel immediately
+func (t *Tracker) Leave(channel, userID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, present := t.channels[channel][userID]; present {
+		delete(t.channels[channel], userID)
+		t.broadcast(Event{Type: Leave, Channel: channel, UserID: userID, At: time.Now()})
+	}
+}
+
+// Online returns the users currently present in the channel
+func (t *Tracker) Online(channel string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	cutoff := time.Now().Add(-t.ttl)
+	users := make([]string, 0, len(t.channels[channel]))
+	for userID, seen := range t.channels[channel] {
+		if seen.After(cutoff) {
+			users = append(users, userID)
+		}
+	}
+	sort.Strings(users)
+	return users
+}
+
+// Subscribe returns a channel of presence events and a function to stop the
+// subscription. Slow subscribers miss events rather than block the tracker.
+func (t *Tracker) Subscribe(channel string) (<-chan Event, func()) {
+	ch := make(chan Event, 16)
+
+	t.mu.Lock()
+	if t.subscribers[channel] == nil {
+		t.subscribers[channel] = make(map[chan Event]struct{})
+	}
+	t.subscribers[channel][ch] = struct{}{}
+	t.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			t.mu.Lock()
+			delete(t.subscribers[channel], ch)
+			if len(t.subscribers[channel]) == 0 {
+				delete(t.subscribers, channel)
+			}
+			t.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Start expires users whose heartbeat is older than the TTL until ctx is done
+func (t *Tracker) Start(ctx context.Context) {
+	ticker := time.NewTicker(t.ttl / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.expire()
+		}
+	}
+}
+
+func (t *Tracker) expire() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	cutoff := time.Now().Add(-t.ttl)
+	for channel, users := range t.channels {
+		for userID, seen := range users {
+			if seen.Before(cutoff) {
+				delete(users, userID)
+				t.broadcast(Event{Type: Leave, Channel: channel, UserID: userID, At: time.Now()})
+			}
+		}
+		if len(users) == 0 {
+			delete(t.channels, channel)
+		}
+	}
+}
+
+// broadcast must be called with mu held
+func (t *Tracker) broadcast(e Event) {
+	for ch := range t.subscribers[e.Channel] {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
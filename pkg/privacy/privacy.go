@@ -0,0 +1,222 @@
+package privacy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Action describes how a table's rows are handled on erasure
+type Action string
+
+const (
+	Delete    Action = "delete"    // Remove the user's rows entirely
+	Anonymize Action = "anonymize" // Overwrite the PII columns in place
+)
+
+// Column is a PII-bearing column. Replacement is written when anonymizing;
+// a nil Replacement sets the column to NULL.
+type Column struct {
+	Name        string
+	Replacement any
+}
+
+// Table declares a table holding personal data for a user
+type Table struct {
+	Schema     string
+	Name       string
+	UserColumn string // Column identifying the data subject
+	Columns    []Column
+	Action     Action
+}
+
+// Bundle is the data subject access request export for a single user
+type Bundle struct {
+	UserID      string                      `json:"user_id"`
+	GeneratedAt time.Time                   `json:"generated_at"`
+	Tables      map[string][]map[string]any `json:"tables"`
+}
+
+// Registry holds the PII inventory for a service
+type Registry struct {
+	mu         sync.RWMutex
+	tables     []Table
+	auditTable string
+}
+
+// NewRegistry creates an empty inventory. Export and erasure requests are
+// recorded in auditTable.
+func NewRegistry(auditTable string) *Registry {
+	return &Registry{auditTable: auditTable}
+}
+
+// Register adds a table to the inventory
+func (r *Registry) Register(t Table) error {
+	if t.Name == "" || t.UserColumn == "" {
+		return errors.New("privacy: table name and user column are required")
+	}
+	if len(t.Columns) == 0 {
+		return fmt.Errorf("privacy: table %s declares no PII columns", t.Name)
+	}
+	if t.Action == "" {
+		t.Action = Anonymize
+	}
+	if t.Action != Delete && t.Action != Anonymize {
+		return fmt.Errorf("privacy: unknown action %q for table %s", t.Action, t.Name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tables = append(r.tables, t)
+	return nil
+}
+
+// Tables returns the registered inventory
+func (r *Registry) Tables() []Table {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Table(nil), r.tables...)
+}
+
+// EnsureAuditTable creates the audit table if it doesn't exist
+func (r *Registry) EnsureAuditTable(ctx context.Context, pool *pgxpool.Pool) error {
+	_, err := pool.Exec(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id BIGSERIAL PRIMARY KEY,
+			user_id TEXT NOT NULL,
+			action TEXT NOT NULL,
+			requested_by TEXT NOT NULL,
+			details JSONB NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`, pgx.Identifier(strings.Split(r.auditTable, ".")).Sanitize()))
+	if err != nil {
+		return fmt.Errorf("error creating privacy audit table: %w", err)
+	}
+	return nil
+}
+
+// Export collects every registered PII column for the user in a single
+// consistent snapshot and records the request in the audit table.
+func (r *Registry) Export(ctx context.Context, pool *pgxpool.Pool, userID, requestedBy string) (*Bundle, error) {
+	bundle := &Bundle{
+		UserID:      userID,
+		GeneratedAt: time.Now(),
+		Tables:      make(map[string][]map[string]any),
+	}
+
+	err := r.inTx(ctx, pool, pgx.TxOptions{IsoLevel: pgx.RepeatableRead}, func(tx pgx.Tx) error {
+		details := make(map[string]int64)
+		for _, t := range r.Tables() {
+			names := make([]string, len(t.Columns))
+			for i, c := range t.Columns {
+				names[i] = pgx.Identifier{c.Name}.Sanitize()
+			}
+
+			rows, err := tx.Query(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE %s = $1",
+				strings.Join(names, ", "), t.identifier(), pgx.Identifier{t.UserColumn}.Sanitize()), userID)
+			if err != nil {
+				return fmt.Errorf("error exporting %s: %w", t.Name, err)
+			}
+			records, err := pgx.CollectRows(rows, pgx.RowToMap)
+			if err != nil {
+				return fmt.Errorf("error exporting %s: %w", t.Name, err)
+			}
+
+			bundle.Tables[t.key()] = records
+			details[t.key()] = int64(len(records))
+		}
+		return r.audit(ctx, tx, userID, "export", requestedBy, details)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return bundle, nil
+}
+
+// Erase deletes or anonymizes the user's data in every registered table. All
+// tables are processed in one transaction along with the audit record, so a
+// failure leaves the data untouched.
+func (r *Registry) Erase(ctx context.Context, pool *pgxpool.Pool, userID, requestedBy string) error {
+	return r.inTx(ctx, pool, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		details := make(map[string]int64)
+		for _, t := range r.Tables() {
+			var (
+				query string
+				args  = []any{userID}
+			)
+
+			switch t.Action {
+			case Delete:
+				query = fmt.Sprintf("DELETE FROM %s WHERE %s = $1",
+					t.identifier(), pgx.Identifier{t.UserColumn}.Sanitize())
+			case Anonymize:
+				sets := make([]string, len(t.Columns))
+				for i, c := range t.Columns {
+					sets[i] = fmt.Sprintf("%s = $%d", pgx.Identifier{c.Name}.Sanitize(), i+2)
+					args = append(args, c.Replacement)
+				}
+				query = fmt.Sprintf("UPDATE %s SET %s WHERE %s = $1",
+					t.identifier(), strings.Join(sets, ", "), pgx.Identifier{t.UserColumn}.Sanitize())
+			}
+
+			tag, err := tx.Exec(ctx, query, args...)
+			if err != nil {
+				return fmt.Errorf("error erasing %s: %w", t.Name, err)
+			}
+			details[t.key()] = tag.RowsAffected()
+		}
+		return r.audit(ctx, tx, userID, "erase", requestedBy, details)
+	})
+}
+
+func (r *Registry) audit(ctx context.Context, tx pgx.Tx, userID, action, requestedBy string, details map[string]int64) error {
+	payload, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Errorf("error encoding audit details: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, fmt.Sprintf(
+		"INSERT INTO %s (user_id, action, requested_by, details) VALUES ($1, $2, $3, $4)",
+		pgx.Identifier(strings.Split(r.auditTable, ".")).Sanitize()),
+		userID, action, requestedBy, payload,
+	)
+	if err != nil {
+		return fmt.Errorf("error writing privacy audit record: %w", err)
+	}
+	return nil
+}
+
+func (r *Registry) inTx(ctx context.Context, pool *pgxpool.Pool, opts pgx.TxOptions, fn func(pgx.Tx) error) error {
+	tx, err := pool.BeginTx(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (t Table) identifier() string {
+	if t.Schema != "" {
+		return pgx.Identifier{t.Schema, t.Name}.Sanitize()
+	}
+	return pgx.Identifier{t.Name}.Sanitize()
+}
+
+func (t Table) key() string {
+	if t.Schema != "" {
+		return t.Schema + "." + t.Name
+	}
+	return t.Name
+}
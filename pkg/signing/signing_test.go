@@ -0,0 +1,190 @@
+package signing
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var testKeys = map[string][]byte{"k1": []byte("secret-one")}
+
+func signedRequest(t *testing.T, method, target, body string) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if err := NewSigner("k1", testKeys["k1"]).Sign(req); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return req
+}
+
+func TestVerifyAcceptsSignedRequest(t *testing.T) {
+	req := signedRequest(t, http.MethodPost, "/callback?a=1", `{"ok":true}`)
+
+	if err := NewVerifier(testKeys, time.Minute, nil).Verify(req); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	body, _ := io.ReadAll(req.Body)
+	if string(body) != `{"ok":true}` {
+		t.Errorf("body after Verify = %q, want it preserved", body)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(*http.Request)
+		want   error
+	}{
+		{"missing signature", func(r *http.Request) { r.Header.Del(HeaderSignature) }, ErrMissingSignature},
+		{"unknown key", func(r *http.Request) { r.Header.Set(HeaderKeyID, "k2") }, ErrUnknownKey},
+		{"bad signature", func(r *http.Request) { r.Header.Set(HeaderSignature, strings.Repeat("0", 64)) }, ErrBadSignature},
+		{"malformed timestamp", func(r *http.Request) { r.Header.Set(HeaderTimestamp, "soon") }, ErrBadSignature},
+		{"changed nonce", func(r *http.Request) { r.Header.Set(HeaderNonce, "other") }, ErrBadSignature},
+		{"changed path", func(r *http.Request) { r.URL.Path = "/other" }, ErrBadSignature},
+		{"changed query", func(r *http.Request) { r.URL.RawQuery = "a=2" }, ErrBadSignature},
+		{"changed method", func(r *http.Request) { r.Method = http.MethodPut }, ErrBadSignature},
+		{"changed body", func(r *http.Request) { r.Body = io.NopCloser(strings.NewReader(`{"ok":false}`)) }, ErrBadSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := signedRequest(t, http.MethodPost, "/callback?a=1", `{"ok":true}`)
+			tt.tamper(req)
+
+			err := NewVerifier(testKeys, time.Minute, nil).Verify(req)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Verify = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsExpired(t *testing.T) {
+	req := signedRequest(t, http.MethodGet, "/callback", "")
+
+	v := NewVerifier(testKeys, time.Minute, nil)
+	v.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if err := v.Verify(req); !errors.Is(err, ErrExpired) {
+		t.Errorf("Verify = %v, want %v", err, ErrExpired)
+	}
+}
+
+func TestVerifyRejectsReplay(t *testing.T) {
+	v := NewVerifier(testKeys, time.Minute, nil)
+	req := signedRequest(t, http.MethodPost, "/callback", "payload")
+	replay := req.Clone(req.Context())
+	replay.Body = io.NopCloser(strings.NewReader("payload"))
+
+	if err := v.Verify(req); err != nil {
+		t.Fatalf("first Verify: %v", err)
+	}
+	if err := v.Verify(replay); !errors.Is(err, ErrReplayed) {
+		t.Errorf("replayed Verify = %v, want %v", err, ErrReplayed)
+	}
+}
+
+func TestVerifyForgeryDoesNotBurnNonce(t *testing.T) {
+	v := NewVerifier(testKeys, time.Minute, nil)
+	req := signedRequest(t, http.MethodPost, "/callback", "payload")
+
+	forged := req.Clone(req.Context())
+	forged.Body = io.NopCloser(strings.NewReader("forged"))
+	if err := v.Verify(forged); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("forged Verify = %v, want %v", err, ErrBadSignature)
+	}
+	if err := v.Verify(req); err != nil {
+		t.Errorf("genuine Verify after forgery: %v", err)
+	}
+}
+
+func TestVerifyLimitsBody(t *testing.T) {
+	req := signedRequest(t, http.MethodPost, "/callback", strings.Repeat("x", 100))
+
+	err := NewVerifier(testKeys, time.Minute, nil).WithMaxBodyBytes(10).Verify(req)
+	if !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("Verify = %v, want %v", err, ErrBodyTooLarge)
+	}
+}
+
+func TestMemoryNonceStoreExpires(t *testing.T) {
+	now := time.Now()
+	s := NewMemoryNonceStore()
+	s.now = func() time.Time { return now }
+
+	if !s.Use("a", now.Add(time.Minute)) {
+		t.Fatal("first Use of a = false")
+	}
+	if s.Use("a", now.Add(time.Minute)) {
+		t.Error("second Use of a = true before expiry")
+	}
+
+	now = now.Add(2 * time.Minute)
+	s.Use("b", now.Add(time.Minute)) // Triggers the sweep
+	if _, ok := s.nonces["a"]; ok {
+		t.Error("expired nonce a was not swept")
+	}
+	if !s.Use("a", now.Add(time.Minute)) {
+		t.Error("Use of a after expiry = false")
+	}
+}
+
+func TestTransportSignsRequests(t *testing.T) {
+	v := NewVerifier(testKeys, time.Minute, nil)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := v.Verify(r); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: NewSigner("k1", testKeys["k1"]).Transport(nil)}
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/callback?x=1", strings.NewReader("payload"))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusNoContent)
+	}
+	if req.Header.Get(HeaderSignature) != "" {
+		t.Error("Transport modified the caller's request")
+	}
+}
+
+func TestMiddlewareStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	v := NewVerifier(testKeys, time.Minute, nil).WithMaxBodyBytes(10)
+	r := gin.New()
+	r.POST("/callback", v.Middleware(), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{"signed", signedRequest(t, http.MethodPost, "/callback", "small"), http.StatusNoContent},
+		{"unsigned", httptest.NewRequest(http.MethodPost, "/callback", nil), http.StatusUnauthorized},
+		{"too large", signedRequest(t, http.MethodPost, "/callback", strings.Repeat("x", 100)), http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, tt.req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package signing
+
+import (
+	"container/heap"
+	"crypto/hmac"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultMaxBodyBytes is the largest body a Verifier reads before the MAC is checked
+const DefaultMaxBodyBytes = 1 << 20
+
+// NonceStore remembers nonces for the verification window to reject replays
+type NonceStore interface {
+	// Use records nonce and reports false if it was already recorded
+	Use(nonce string, expires time.Time) bool
+}
+
+// MemoryNonceStore is a NonceStore for a single instance. Services running
+// several instances need a shared store to catch replays across them.
+type MemoryNonceStore struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+	expiry nonceHeap // Soonest expiry first, so sweeping only touches expired nonces
+	now    func() time.Time
+}
+
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{nonces: make(map[string]time.Time), now: time.Now}
+}
+
+func (s *MemoryNonceStore) Use(nonce string, expires time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for len(s.expiry) > 0 && now.After(s.expiry[0].expires) {
+		delete(s.nonces, heap.Pop(&s.expiry).(nonceEntry).nonce)
+	}
+
+	if _, seen := s.nonces[nonce]; seen {
+		return false
+	}
+	s.nonces[nonce] = expires
+	heap.Push(&s.expiry, nonceEntry{nonce: nonce, expires: expires})
+	return true
+}
+
+type nonceEntry struct {
+	nonce   string
+	expires time.Time
+}
+
+type nonceHeap []nonceEntry
+
+func (h nonceHeap) Len() int           { return len(h) }
+func (h nonceHeap) Less(i, j int) bool { return h[i].expires.Before(h[j].expires) }
+func (h nonceHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *nonceHeap) Push(x any)        { *h = append(*h, x.(nonceEntry)) }
+
+func (h *nonceHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
+
+// Verifier checks signatures made by Signer
+type Verifier struct {
+	keys    map[string][]byte // Secrets by key ID, several during rotation
+	window  time.Duration
+	nonces  NonceStore
+	maxBody int64
+	now     func() time.Time
+}
+
+// NewVerifier accepts signatures by any of keys made within window of now
+// on bodies up to DefaultMaxBodyBytes
+func NewVerifier(keys map[string][]byte, window time.Duration, nonces NonceStore) *Verifier {
+	if nonces == nil {
+		nonces = NewMemoryNonceStore()
+	}
+	return &Verifier{keys: keys, window: window, nonces: nonces, maxBody: DefaultMaxBodyBytes, now: time.Now}
+}
+
+// WithMaxBodyBytes changes the largest body the verifier will read
+func (v *Verifier) WithMaxBodyBytes(n int64) *Verifier {
+	v.maxBody = n
+	return v
+}
+
+// Verify checks the signature on req, leaving the body readable
+func (v *Verifier) Verify(req *http.Request) error {
+	keyID := req.Header.Get(HeaderKeyID)
+	timestamp := req.Header.Get(HeaderTimestamp)
+	nonce := req.Header.Get(HeaderNonce)
+	signature := req.Header.Get(HeaderSignature)
+	if keyID == "" || timestamp == "" || nonce == "" || signature == "" {
+		return ErrMissingSignature
+	}
+
+	secret, ok := v.keys[keyID]
+	if !ok {
+		return ErrUnknownKey
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrBadSignature
+	}
+	signedAt := time.Unix(unix, 0)
+	if d := v.now().Sub(signedAt); d > v.window || d < -v.window {
+		return ErrExpired
+	}
+
+	// Key ID and timestamp aren't secret, so bound what an unauthenticated
+	// caller can make us buffer
+	body, err := readBody(req, v.maxBody)
+	if err != nil {
+		return err
+	}
+	expected := sign(secret, canonical(req, timestamp, nonce, body))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrBadSignature
+	}
+
+	// Only record nonces of authentic requests so forgeries can't burn them
+	if !v.nonces.Use(keyID+":"+nonce, signedAt.Add(v.window)) {
+		return ErrReplayed
+	}
+	return nil
+}
+
+// Middleware rejects requests without a valid signature
+func (v *Verifier) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := v.Verify(c.Request); err != nil {
+			var status int
+			switch {
+			case errors.Is(err, ErrMissingSignature), errors.Is(err, ErrUnknownKey),
+				errors.Is(err, ErrExpired), errors.Is(err, ErrReplayed), errors.Is(err, ErrBadSignature):
+				status = http.StatusUnauthorized
+			case errors.Is(err, ErrBodyTooLarge):
+				status = http.StatusRequestEntityTooLarge
+			default:
+				status = http.StatusBadRequest // Unreadable body
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
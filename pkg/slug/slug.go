@@ -0,0 +1,182 @@
+package slug
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	ErrEmpty     = errors.New("slug: input produces an empty slug")
+	ErrExhausted = errors.New("slug: no free slug found")
+)
+
+// Letters that don't decompose into a base letter plus combining marks
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'Æ': "ae", 'ø': "o", 'Ø': "o", 'œ': "oe", 'Œ': "oe",
+	'ł': "l", 'Ł': "l", 'đ': "d", 'Đ': "d", 'þ': "th", 'Þ': "th", 'ð': "d", 'Ð': "d",
+	'&': " and ",
+}
+
+// Make converts s to a lowercase ASCII slug, joining words with hyphens and
+// truncating to maxLen (0 means no limit) on a word boundary where possible.
+func Make(s string, maxLen int) string {
+	var b strings.Builder
+	hyphen := false
+
+	for _, r := range norm.NFKD.String(s) {
+		if t, ok := transliterations[r]; ok {
+			for _, tr := range t {
+				hyphen = write(&b, tr, hyphen)
+			}
+			continue
+		}
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		hyphen = write(&b, r, hyphen)
+	}
+
+	out := strings.Trim(b.String(), "-")
+	if maxLen > 0 && len(out) > maxLen {
+		out = out[:maxLen]
+		if i := strings.LastIndex(out, "-"); i > maxLen/2 {
+			out = out[:i]
+		}
+		out = strings.Trim(out, "-")
+	}
+	return out
+}
+
+func write(b *strings.Builder, r rune, hyphen bool) bool {
+	r = unicode.ToLower(r)
+	if r <= unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+		b.WriteRune(r)
+		return false
+	}
+	if !hyphen && b.Len() > 0 {
+		b.WriteByte('-')
+	}
+	return true
+}
+
+// Generator produces slugs that are unique within a table column
+type Generator struct {
+	pool     *pgxpool.Pool
+	table    string
+	column   string
+	maxLen   int
+	attempts int
+	reserved map[string]bool
+}
+
+// NewGenerator creates a generator for table.column. Reserved slugs (route
+// names like "new", "admin", "api") are never returned.
+func NewGenerator(pool *pgxpool.Pool, table, column string, maxLen int, reserved ...string) *Generator {
+	g := &Generator{
+		pool:     pool,
+		table:    table,
+		column:   column,
+		maxLen:   maxLen,
+		attempts: 100,
+		reserved: make(map[string]bool),
+	}
+	for _, r := range reserved {
+		g.reserved[r] = true
+	}
+	return g
+}
+
+// Unique returns the first free slug for s, appending -2, -3, ... on collision
+func (g *Generator) Unique(ctx context.Context, s string) (string, error) {
+	base, err := g.base(s)
+	if err != nil {
+		return "", err
+	}
+
+	for i := 1; i <= g.attempts; i++ {
+		candidate, ok := g.candidate(base, i)
+		if !ok {
+			break
+		}
+		if g.reserved[candidate] {
+			continue
+		}
+
+		var exists bool
+		err := g.pool.QueryRow(ctx, fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE %s = $1)",
+			pgx.Identifier(strings.Split(g.table, ".")).Sanitize(), pgx.Identifier{g.column}.Sanitize()),
+			candidate,
+		).Scan(&exists)
+		if err != nil {
+			return "", fmt.Errorf("error checking slug: %w", err)
+		}
+		if !exists {
+			return candidate, nil
+		}
+	}
+	return "", ErrExhausted
+}
+
+// Insert calls insert with successive candidates until it succeeds without a
+// unique violation. Use it where a concurrent writer could claim the slug
+// between Unique and the insert.
+func (g *Generator) Insert(ctx context.Context, s string, insert func(ctx context.Context, slug string) error) (string, error) {
+	base, err := g.base(s)
+	if err != nil {
+		return "", err
+	}
+
+	for i := 1; i <= g.attempts; i++ {
+		candidate, ok := g.candidate(base, i)
+		if !ok {
+			break
+		}
+		if g.reserved[candidate] {
+			continue
+		}
+
+		err := insert(ctx, candidate)
+		if err == nil {
+			return candidate, nil
+		}
+
+		var pgErr *pgconn.PgError
+		if !errors.As(err, &pgErr) || pgErr.Code != "23505" {
+			return "", err
+		}
+	}
+	return "", ErrExhausted
+}
+
+func (g *Generator) base(s string) (string, error) {
+	base := Make(s, g.maxLen)
+	if base == "" {
+		return "", ErrEmpty
+	}
+	return base, nil
+}
+
+// candidate returns the i-th slug for base, or false once the numeric suffix
+// no longer fits within maxLen alongside at least one character of base
+func (g *Generator) candidate(base string, i int) (string, bool) {
+	if i == 1 {
+		return base, true
+	}
+	suffix := fmt.Sprintf("-%d", i)
+	if g.maxLen > 0 && len(base)+len(suffix) > g.maxLen {
+		keep := g.maxLen - len(suffix)
+		if keep < 1 {
+			return "", false
+		}
+		base = strings.TrimRight(base[:keep], "-")
+	}
+	return base + suffix, true
+}
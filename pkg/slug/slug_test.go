@@ -0,0 +1,33 @@
+package slug
+
+import "testing"
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     string
+		maxLen int
+		want   string
+	}{
+		{"words", "Hello World", 0, "hello-world"},
+		{"punctuation and spacing", "  --Hello,   World!!  ", 0, "hello-world"},
+		{"digits", "Route 66", 0, "route-66"},
+		{"accents", "Crème Brûlée", 0, "creme-brulee"},
+		{"transliteration", "Straße Æon", 0, "strasse-aeon"},
+		{"ampersand", "Tom & Jerry", 0, "tom-and-jerry"},
+		{"no ascii letters", "日本語", 0, ""},
+		{"empty", "", 0, ""},
+		{"truncates on word boundary", "the quick brown fox", 12, "the-quick"},
+		{"truncates before trailing hyphen", "hello world", 6, "hello"},
+		{"truncates mid word without a late boundary", "ab cdefghijkl", 8, "ab-cdefg"},
+		{"shorter than limit", "hello", 10, "hello"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Make(tt.in, tt.maxLen); got != tt.want {
+				t.Errorf("Make(%q, %d) = %q, want %q", tt.in, tt.maxLen, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package version
+
+import (
+	"runtime/debug"
+	"sort"
+
+	"github.com/ranson21/ranor-common/pkg/database/config"
+	"github.com/ranson21/ranor-common/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Summary is the effective startup configuration worth having in the logs
+// during incident triage. Secrets are never included.
+type Summary struct {
+	Info
+	Environment  string
+	Database     *config.DatabaseConfig
+	Middleware   []string
+	FeatureFlags map[string]bool
+	Dependencies []string // module@version pairs, read from the build info when nil
+}
+
+// Dependencies returns the module@version of every dependency linked into the
+// binary, noting replacements as module@version => replacement@version. It
+// returns nil when the binary carries no build info.
+func Dependencies() []string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil
+	}
+
+	deps := make([]string, 0, len(info.Deps))
+	for _, dep := range info.Deps {
+		d := dep.Path + "@" + dep.Version
+		if r := dep.Replace; r != nil {
+			d += " => " + r.Path
+			if r.Version != "" {
+				d += "@" + r.Version
+			}
+		}
+		deps = append(deps, d)
+	}
+	sort.Strings(deps)
+	return deps
+}
+
+// LogSummary emits the summary as a single structured log line. Call it once
+// after the service is fully configured.
+func LogSummary(log logger.Logger, s Summary) {
+	fields := []zap.Field{
+		zap.String("service", s.Service),
+		zap.String("version", s.Version),
+		zap.String("commit", s.Commit),
+		zap.String("env", s.Environment),
+		zap.String("config_fingerprint", s.Fingerprint),
+		zap.Strings("middleware", s.Middleware),
+	}
+
+	if db := s.Database; db != nil {
+		fields = append(fields,
+			zap.String("db_host", db.Host),
+			zap.String("db_name", db.DBName),
+			zap.String("db_schema", db.Schema),
+			zap.Int32("db_max_conns", db.MaxConns),
+			zap.Int32("db_min_conns", db.MinConns),
+			zap.Bool("db_iam_auth", db.UseIAMAuth),
+		)
+	}
+
+	if len(s.FeatureFlags) > 0 {
+		enabled := make([]string, 0, len(s.FeatureFlags))
+		for flag, on := range s.FeatureFlags {
+			if on {
+				enabled = append(enabled, flag)
+			}
+		}
+		sort.Strings(enabled)
+		fields = append(fields, zap.Strings("feature_flags", enabled))
+	}
+
+	deps := s.Dependencies
+	if deps == nil {
+		deps = Dependencies()
+	}
+	if len(deps) > 0 {
+		fields = append(fields, zap.Strings("dependencies", deps))
+	}
+
+	log.Info("startup summary", fields...)
+}
@@ -0,0 +1,117 @@
+package version
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ranson21/ranor-common/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Field names containing any of these are treated as secrets and left out of
+// the fingerprint. Fields can also opt out with a `fingerprint:"-"` tag.
+var secretMarkers = []string{"password", "secret", "token", "apikey", "api_key", "privatekey", "credential"}
+
+// Info describes the running build and its effective configuration
+type Info struct {
+	Service     string `json:"service"`
+	Version     string `json:"version"`
+	Commit      string `json:"commit,omitempty"`
+	Fingerprint string `json:"config_fingerprint"`
+}
+
+// Fingerprint returns a stable SHA-256 over the non-secret fields of the
+// given config values. Replicas running identical config produce the same
+// fingerprint regardless of field or map ordering.
+func Fingerprint(configs ...any) string {
+	values := make([]any, len(configs))
+	for i, c := range configs {
+		values[i] = normalize(reflect.ValueOf(c))
+	}
+
+	// encoding/json sorts map keys, which makes the output deterministic
+	b, err := json.Marshal(values)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func normalize(v reflect.Value) any {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		out := make(map[string]any)
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() || f.Tag.Get("fingerprint") == "-" || isSecret(f.Name) {
+				continue
+			}
+			out[f.Name] = normalize(v.Field(i))
+		}
+		return out
+	case reflect.Map:
+		out := make(map[string]any)
+		iter := v.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			if isSecret(key) {
+				continue
+			}
+			out[key] = normalize(iter.Value())
+		}
+		return out
+	case reflect.Slice, reflect.Array:
+		out := make([]any, v.Len())
+		for i := range out {
+			out[i] = normalize(v.Index(i))
+		}
+		return out
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer, reflect.Invalid:
+		return nil
+	default:
+		return v.Interface()
+	}
+}
+
+func isSecret(name string) bool {
+	lower := strings.ToLower(name)
+	for _, marker := range secretMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handler serves the build info, e.g. mounted at /version
+func Handler(info Info) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, info)
+	}
+}
+
+// LogStartup logs the build info once so the fingerprint appears in the
+// startup logs of every replica
+func LogStartup(log logger.Logger, info Info) {
+	log.Info("service starting",
+		zap.String("service", info.Service),
+		zap.String("version", info.Version),
+		zap.String("commit", info.Commit),
+		zap.String("config_fingerprint", info.Fingerprint),
+	)
+}